@@ -0,0 +1,123 @@
+package DB
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// Decimal 以字符串保存的定点数，用于金额等字段，避免浮点误差
+type Decimal string
+
+func (d *Decimal) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*d = ""
+	case []byte:
+		*d = Decimal(v)
+	case string:
+		*d = Decimal(v)
+	case int64:
+		*d = Decimal(strconv.FormatInt(v, 10))
+	case float64:
+		*d = Decimal(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		return fmt.Errorf("unsupported Scan, storing driver.Value type %T into type DB.Decimal", src)
+	}
+	return nil
+}
+
+func (d Decimal) Value() (driver.Value, error) {
+	if d == "" {
+		return nil, nil
+	}
+	return string(d), nil
+}
+
+func (d Decimal) String() string {
+	return string(d)
+}
+
+// Rat 转为精确有理数，用于金额计算
+func (d Decimal) Rat() (*big.Rat, bool) {
+	if d == "" {
+		return new(big.Rat), true
+	}
+	return new(big.Rat).SetString(string(d))
+}
+
+func (d Decimal) Float64() (float64, error) {
+	if d == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(string(d), 64)
+}
+
+/*
+Sum 求和，金额字段建议使用 Decimal 或 string 接收
+
+	total, err := DB.Sum[DB.Decimal](DB.QiaoDB().Table("orders").Find("status = ?", 1), "amount")
+
+sqlite 的 decimal 列按 REAL 保存，求和在浮点数上进行；Decimal / string 接收时结果按文本读取，
+为 15 位有效数字，精度要求高时请将金额存为整数（分）
+*/
+func Sum[T any](mapper *Mapper, field string) (T, error) {
+	return aggregate[T](mapper, "sum", field)
+}
+
+// Avg 平均值
+func Avg[T any](mapper *Mapper, field string) (T, error) {
+	return aggregate[T](mapper, "avg", field)
+}
+
+// Min 最小值
+func Min[T any](mapper *Mapper, field string) (T, error) {
+	return aggregate[T](mapper, "min", field)
+}
+
+// Max 最大值
+func Max[T any](mapper *Mapper, field string) (T, error) {
+	return aggregate[T](mapper, "max", field)
+}
+
+// 聚合查询，无数据或结果为 NULL 时返回零值
+func aggregate[T any](mapper *Mapper, fn, field string) (val T, err error) {
+	mapper.Debris.field = fmt.Sprintf("%s(%s)", fn, field)
+	switch any(val).(type) {
+	case Decimal, string:
+		// sqlite 的聚合结果为浮点数，转为文本读取，避免 30.299999999999997
+		if mapper.dialect() == "sqlite" {
+			mapper.Debris.field = fmt.Sprintf("cast(%s as text)", mapper.Debris.field)
+		}
+	}
+	if mapper.Complete.Sql, err = mapper.getSql(); err != nil {
+		mapper.log("get sql error").logERROR(err)
+		return
+	}
+	mapper.debug(fn)
+	if mapper.sqlRows, err = mapper.Read().QueryContext(mapper.context(), mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
+		return
+	}
+	defer mapper.closeRows(&err)
+	if !mapper.sqlRows.Next() {
+		return val, mapper.sqlRows.Err()
+	}
+	var v sql.Null[T]
+	if err = mapper.sqlRows.Scan(&v); err != nil {
+		mapper.log("scan aggregate error").logERROR(err)
+		return
+	}
+	return v.V, nil
+}
+
+/*
+GroupList 分组查询，结果读入结构体切片
+
+	@list *[]struct；--字段 db 标签可写聚合表达式，如 db:"sum(amount) as total"
+	@group string；--分组字段
+*/
+func (mapper *Mapper) GroupList(list any, group string) error {
+	return mapper.GroupBy(group).GetList(list)
+}
@@ -0,0 +1,253 @@
+package DB
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chris-liu-zh/qiao/tools"
+)
+
+// AuditRecord 一次写操作的审计记录，前后镜像在同一事务内读取
+type AuditRecord struct {
+	Table  string           `json:"table"`
+	Action string           `json:"action"` // update / delete
+	Actor  string           `json:"actor"`
+	Sql    string           `json:"sql"`
+	Args   []any            `json:"args"`
+	Before []map[string]any `json:"before"`
+	After  []map[string]any `json:"after"`
+	Time   time.Time        `json:"time"`
+}
+
+// AuditSink 审计记录写入方，tx 为本次写操作所在事务，返回错误时写操作回滚
+type AuditSink func(ctx context.Context, tx *Begin, rec *AuditRecord) error
+
+type auditor struct {
+	sink AuditSink
+	key  string
+}
+
+var auditTables sync.Map
+
+type actorKey struct{}
+
+// WithActor 在 context 中记录操作人，审计时读取
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom 读取 context 中的操作人
+func ActorFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// Context 设置请求上下文，审计时从中读取操作人
+func (mapper *Mapper) Context(ctx context.Context) *Mapper {
+	mapper.ctx = ctx
+	return mapper
+}
+
+func (mapper *Mapper) context() context.Context {
+	if mapper.ctx == nil {
+		return context.Background()
+	}
+	return mapper.ctx
+}
+
+/*
+Audit 为模型开启审计，对 Update / UpdateAffected / Del / DelAffected 生效
+
+	@sink AuditSink；--审计写入方，如 AuditTable("audit_log")，为 nil 时关闭审计
+	@models 结构体指针或表名；--结构体以 Autoincrement 字段为主键，表名默认主键为 id
+*/
+func Audit(sink AuditSink, models ...any) {
+	for _, model := range models {
+		table, key := auditModel(model)
+		if table == "" {
+			continue
+		}
+		if sink == nil {
+			auditTables.Delete(table)
+			continue
+		}
+		auditTables.Store(table, auditor{sink: sink, key: key})
+	}
+}
+
+func auditModel(model any) (table, key string) {
+	if name, ok := model.(string); ok {
+		return name, "id"
+	}
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return "", ""
+	}
+	key = "id"
+	for i := range t.NumField() {
+		fields := strings.Split(t.Field(i).Tag.Get("db"), ";")
+		if slices.Contains(fields, "Autoincrement") {
+			if key = getColumn(fields); key == "" {
+				key = tools.CamelCaseToUdnderscore(t.Field(i).Name)
+			}
+			break
+		}
+	}
+	return tools.CamelCaseToUdnderscore(t.Name()), key
+}
+
+/*
+AuditTable 将审计记录写入表，与写操作同一事务
+
+	表结构：table_name, action, actor, sql_text, args, before_data, after_data, created_at
+*/
+func AuditTable(table string) AuditSink {
+	insert := fmt.Sprintf("insert into %s (table_name,action,actor,sql_text,args,before_data,after_data,created_at) values (?,?,?,?,?,?,?,?)", table)
+	return func(ctx context.Context, tx *Begin, rec *AuditRecord) error {
+		values := []any{rec.Table, rec.Action, rec.Actor, rec.Sql}
+		for _, v := range []any{rec.Args, rec.Before, rec.After} {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			values = append(values, string(b))
+		}
+		values = append(values, rec.Time)
+		_, err := tx.ExecContext(ctx, insert, values...)
+		return err
+	}
+}
+
+func (mapper *Mapper) auditor() (auditor, bool) {
+	a, ok := auditTables.Load(mapper.Debris.table)
+	if !ok {
+		return auditor{}, false
+	}
+	return a.(auditor), true
+}
+
+// 在事务内读取前镜像、执行写操作、读取后镜像并写入审计记录
+func (mapper *Mapper) auditExec(a auditor, action string) (r sql.Result, err error) {
+	db := mapper.Write()
+	if db == nil {
+		return nil, ErrNoConn
+	}
+	ctx := mapper.context()
+	tx := db.begin(mapper)
+	if err = tx.Err; err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	rec := &AuditRecord{
+		Table:  mapper.Debris.table,
+		Action: action,
+		Actor:  ActorFrom(ctx),
+		Sql:    mapper.Complete.Sql,
+		Args:   mapper.Complete.Args,
+		Time:   time.Now(),
+	}
+	// set 参数在前，其后为 where 参数
+	whereArgs := mapper.Complete.Args[min(len(mapper.compose.setArgs), len(mapper.Complete.Args)):]
+	before := mapper.auditBefore(db.Conf.Type)
+	if rec.Before, err = auditRows(ctx, tx, before, whereArgs); err != nil {
+		return
+	}
+
+	if r, err = tx.ExecContext(ctx, mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
+		return
+	}
+
+	if action == "update" && len(rec.Before) > 0 {
+		// 按主键重新读取，避免更新了条件字段后读不到
+		after, args := before, whereArgs
+		if keys := auditKeys(rec.Before, a.key); keys != nil {
+			after = fmt.Sprintf("select * from %s where %s in (%s)", mapper.Debris.table, a.key, Placeholders(len(keys)))
+			args = keys
+		}
+		if rec.After, err = auditRows(ctx, tx, after, args); err != nil {
+			return
+		}
+	}
+	if err = a.sink(ctx, tx, rec); err != nil {
+		db.log("audit sink error", mapper.Complete.Sql, mapper.Complete.Args...).logERROR(err)
+	}
+	return
+}
+
+// 前镜像查询：沿用写语句的别名与连接，并锁定待修改的行直到事务结束
+func (mapper *Mapper) auditBefore(dbType string) string {
+	table, ref := mapper.Debris.table, mapper.Debris.table
+	if mapper.Debris.alias != "" {
+		table += " " + mapper.Debris.alias
+		ref = mapper.Debris.alias
+	}
+	switch dbType {
+	case "mssql":
+		return fmt.Sprintf("select %s.* from %s with (updlock, rowlock)%s %s", ref, table, mapper.Debris.join, mapper.Debris.where)
+	case "pgsql":
+		return fmt.Sprintf("select %s.* from %s%s %s for update of %s", ref, table, mapper.Debris.join, mapper.Debris.where, ref)
+	case "mysql":
+		return fmt.Sprintf("select %s.* from %s%s %s for update", ref, table, mapper.Debris.join, mapper.Debris.where)
+	default:
+		// sqlite 写事务本身串行
+		return fmt.Sprintf("select %s.* from %s%s %s", ref, table, mapper.Debris.join, mapper.Debris.where)
+	}
+}
+
+func auditRows(ctx context.Context, tx *Begin, sqlStr string, args []any) (list []map[string]any, err error) {
+	rows, err := tx.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return
+	}
+	defer tools.DeferErr(&err, rows.Close)
+	columns, err := rows.Columns()
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var row map[string]any
+		if row, err = scanMapRow(rows, columns); err != nil {
+			return
+		}
+		for k, v := range row {
+			if b, ok := v.([]byte); ok {
+				row[k] = string(b)
+			}
+		}
+		list = append(list, row)
+	}
+	return list, rows.Err()
+}
+
+// 前镜像中的主键值，任一行缺少主键时返回 nil
+func auditKeys(rows []map[string]any, key string) []any {
+	keys := make([]any, 0, len(rows))
+	for _, row := range rows {
+		v, ok := row[key]
+		if !ok || v == nil {
+			return nil
+		}
+		keys = append(keys, v)
+	}
+	return keys
+}
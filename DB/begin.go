@@ -8,32 +8,47 @@
 package DB
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/chris-liu-zh/qiao/tools"
 )
 
 type Begin struct {
-	Tx     *sql.Tx
-	stmt   *sql.Stmt
-	Err    error
-	Title  string
-	Mapper *Mapper
+	Tx      *sql.Tx // 直接执行的语句不经过拦截器，错误未归类，应使用 ExecSql / ExecContext
+	stmt    *sql.Stmt
+	Err     error
+	Title   string
+	Mapper  *Mapper
+	role    string
+	dialect string
+	query   string // Prepare 的语句
+	db      *ConnDB
 }
 
 // Begin 开始事务
 func (mapper *Mapper) Begin() *Begin {
+	return mapper.Write().begin(mapper)
+}
+
+// BeginTx 在指定连接上开始事务，事务中的语句经过拦截器链
+func (db *ConnDB) BeginTx(ctx context.Context) *Begin {
+	return db.begin(QiaoDB().Context(ctx))
+}
+
+func (db *ConnDB) begin(mapper *Mapper) *Begin {
 	tx := &Begin{
 		Mapper: mapper,
 	}
-	db := mapper.Write()
 	if db == nil {
 		tx.Err = ErrNoConn
 		return tx
 	}
-	tx.Title = db.Conf.Title
-	if tx.Tx, tx.Err = db.DBFunc.Conn.Begin(); tx.Err == nil {
-		return tx
+	tx.db, tx.Title, tx.role, tx.dialect = db, db.Conf.Title, db.Conf.Role, db.Conf.Type
+	if tx.Tx, tx.Err = db.DBFunc.Conn.BeginTx(mapper.context(), nil); tx.Err != nil {
+		db.log("begin error", "").logERROR(tx.Err)
+		tx.Err = classifyError(tx.dialect, tx.Err)
 	}
 	return tx
 }
@@ -42,6 +57,7 @@ func (tx *Begin) Prepare(sqlStr string) *Begin {
 	if tx.Err != nil {
 		return tx
 	}
+	tx.query = sqlStr
 	tx.stmt, tx.Err = tx.Tx.Prepare(sqlStr)
 	return tx
 }
@@ -50,10 +66,11 @@ func (tx *Begin) StmtExec(args ...any) *Begin {
 	if tx.Err != nil {
 		return tx
 	}
-	txArgs := handleNull(args...)
-	if _, tx.Err = tx.stmt.Exec(txArgs...); tx.Err != nil {
-		return tx
-	}
+	op := tx.operation(tx.query, handleNull(args...))
+	tx.Err = intercept(tx.Mapper.context(), op, func(ctx context.Context, op *Operation) (err error) {
+		op.Result, err = tx.stmt.ExecContext(ctx, op.Args...)
+		return
+	})
 	return tx
 }
 
@@ -64,16 +81,74 @@ func (tx *Begin) Exec(args ...any) *Begin {
 	if tx.Mapper.Complete.Sql, tx.Err = tx.Mapper.getSql(); tx.Err != nil {
 		return tx
 	}
-	txArgs := handleNull(args...)
-	query := Replace(tx.Mapper.Complete.Sql, "?", tx.Mapper.Debris.sign)
-	if _, tx.Err = tx.Tx.Exec(query, txArgs...); tx.Err != nil {
-		return tx
-	}
+	op := tx.operation(Replace(tx.Mapper.Complete.Sql, "?", tx.Mapper.Debris.sign), handleNull(args...))
+	tx.Err = intercept(tx.Mapper.context(), op, func(ctx context.Context, op *Operation) (err error) {
+		op.Result, err = tx.Tx.ExecContext(ctx, op.Sql, op.Args...)
+		return
+	})
 	return tx
 }
 
+func (tx *Begin) operation(query string, args []any) *Operation {
+	return &Operation{Kind: "exec", Sql: query, Args: args, Dialect: tx.dialect, Role: tx.role, Title: tx.Title, Tx: true}
+}
+
+// ExecSql 在事务中执行 sql，使用 Mapper 的 context
+func (tx *Begin) ExecSql(sqlStr string, args ...any) (sql.Result, error) {
+	return tx.ExecContext(tx.Mapper.context(), sqlStr, args...)
+}
+
+/*
+ExecContext 在事务中执行 sql，? 替换为连接的占位符
+
+	经过拦截器链，错误已按 DBError 归类；不设置 tx.Err，由调用方决定是否回滚
+*/
+func (tx *Begin) ExecContext(ctx context.Context, sqlStr string, args ...any) (r sql.Result, err error) {
+	if tx.Tx == nil {
+		return nil, tx.Err
+	}
+	op := tx.operation(tx.placeholder(sqlStr), handleNull(args...))
+	err = tx.run(ctx, op, func(ctx context.Context, op *Operation) (err error) {
+		op.Result, err = tx.Tx.ExecContext(ctx, op.Sql, op.Args...)
+		return
+	})
+	return op.Result, err
+}
+
+// QueryContext 在事务中查询，与 ExecContext 相同经过拦截器链
+func (tx *Begin) QueryContext(ctx context.Context, sqlStr string, args ...any) (rows *sql.Rows, err error) {
+	if tx.Tx == nil {
+		return nil, tx.Err
+	}
+	op := tx.operation(tx.placeholder(sqlStr), handleNull(args...))
+	op.Kind = "query"
+	err = tx.run(ctx, op, func(ctx context.Context, op *Operation) (err error) {
+		op.Rows, err = tx.Tx.QueryContext(ctx, op.Sql, op.Args...)
+		return
+	})
+	return op.Rows, err
+}
+
+func (tx *Begin) placeholder(sqlStr string) string {
+	if tx.db == nil {
+		return sqlStr
+	}
+	return Replace(sqlStr, "?", tx.db.Sign)
+}
+
+func (tx *Begin) run(ctx context.Context, op *Operation, final Handler) error {
+	if tx.db != nil {
+		tx.db.log("Tx", op.Sql, op.Args...).logDEBUG()
+	}
+	err := intercept(ctx, op, final)
+	if err != nil && tx.db != nil {
+		tx.db.log("tx error", op.Sql, op.Args...).logERROR(err)
+	}
+	return err
+}
+
 func (tx *Begin) Rollback() (err error) {
-	defer tools.DeferErr(&err, tx.stmt.Close)
+	defer tx.closeStmt(&err)
 	if err = tx.Tx.Rollback(); err != nil {
 		return err
 	}
@@ -81,12 +156,53 @@ func (tx *Begin) Rollback() (err error) {
 }
 
 func (tx *Begin) Commit() (err error) {
-	defer tools.DeferErr(&err, tx.stmt.Close)
+	defer tx.closeStmt(&err)
 	if err = tx.Err; err != nil {
 		return err
 	}
 	if err = tx.Tx.Commit(); err != nil {
-		return err
+		return classifyError(tx.dialect, err)
 	}
 	return
 }
+
+// 未调用 Prepare 时没有需要关闭的语句
+func (tx *Begin) closeStmt(err *error) {
+	if tx.stmt != nil {
+		tools.DeferErr(err, tx.stmt.Close)
+	}
+}
+
+/*
+Transaction 在事务中执行 fn，fn 返回 nil 且语句无错误时提交，否则回滚
+
+	每次执行使用新的事务与 Mapper，死锁等可重试错误按 RetryPolicy 重新执行整个 fn，
+	fn 中不应有事务外的副作用
+
+	err := DB.QiaoDB().Transaction(func(tx *DB.Begin) error {
+		_, err := tx.ExecSql("update stock set num = num - 1 where id = ?", 1)
+		return err
+	})
+*/
+func (mapper *Mapper) Transaction(fn func(tx *Begin) error) error {
+	ctx := mapper.context()
+	dialect, title := "", ""
+	if db := mapper.Write(); db != nil {
+		dialect, title = db.Conf.Type, db.Conf.Title
+	}
+	err := withRetry(ctx, dialect, title, "transaction", nil, func() (err error) {
+		m := QiaoDB(OptionsRole(mapper.Role), OptionsDebug(mapper.Complete.Debug)).Context(ctx)
+		tx := m.Begin()
+		if tx.Err != nil {
+			return tx.Err
+		}
+		if err = fn(tx); err == nil {
+			err = tx.Err
+		}
+		if err != nil {
+			return errors.Join(err, tx.Rollback())
+		}
+		return tx.Commit()
+	})
+	return classifyError(dialect, err)
+}
@@ -0,0 +1,288 @@
+package DB
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// 回退为批量 insert 时单条语句最多绑定的参数个数
+const bulkMaxParams = 999
+
+var (
+	ErrBulkColumns = errors.New("bulk load columns is empty")
+	ErrBulkRow     = errors.New("bulk load row length does not match columns")
+	bulkReaderSeq  atomic.Uint64
+	// LOAD DATA 的数据管道，按 Reader 名称登记，测试模式驱动从中读取
+	loadDataReaders sync.Map
+)
+
+// BulkLoad 批量导入，使用写库
+func BulkLoad(table string, columns []string, rows iter.Seq[[]any]) (int64, error) {
+	return QiaoDB().BulkLoad(table, columns, rows)
+}
+
+/*
+BulkLoad 批量导入，整个导入在同一事务中完成
+
+	@table string；--表名
+	@columns []string；--列名，每行的值按列顺序给出
+	@rows iter.Seq[[]any]；--行数据
+
+pgsql 使用 COPY FROM STDIN，mysql 使用 LOAD DATA LOCAL INFILE（需服务端开启 local_infile），
+mssql 使用 bulk copy，sqlite 使用分批的多行 insert
+*/
+func (mapper *Mapper) BulkLoad(table string, columns []string, rows iter.Seq[[]any]) (affected int64, err error) {
+	if len(columns) == 0 {
+		return 0, ErrBulkColumns
+	}
+	var tenantErr error
+	if columns, rows, err = mapper.tenantBulk(table, columns, rows, &tenantErr); err != nil {
+		return 0, err
+	}
+	db := mapper.Write()
+	if db == nil {
+		return 0, ErrNoConn
+	}
+	tx := db.begin(mapper)
+	if tx.Err != nil {
+		return 0, tx.Err
+	}
+	ctx := mapper.context()
+	switch db.Conf.Type {
+	case "pgsql":
+		affected, err = copyIn(ctx, tx, copyInTable(table, columns), len(columns), rows)
+	case "mssql":
+		affected, err = copyIn(ctx, tx, mssql.CopyIn(table, mssql.BulkOptions{}, columns...), len(columns), rows)
+	case "mysql":
+		affected, err = loadData(ctx, tx, table, columns, rows)
+	default:
+		affected, err = bulkInsert(ctx, tx, table, columns, rows)
+	}
+	if err == nil {
+		err = tenantErr
+	}
+	if err != nil {
+		// CopyIn 逐行缓冲时的驱动错误未经过拦截器，在此归类
+		db.log("BulkLoad error", table).logERROR(err)
+		return 0, errors.Join(classifyError(db.Conf.Type, err), tx.Rollback())
+	}
+	if err = tx.Commit(); err != nil {
+		db.log("BulkLoad commit error", table).logERROR(err)
+		return 0, err
+	}
+	return
+}
+
+func copyInTable(table string, columns []string) string {
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		return pq.CopyInSchema(schema, name, columns...)
+	}
+	return pq.CopyIn(table, columns...)
+}
+
+// pgsql、mssql 驱动的 CopyIn 语句：逐行 Exec 只在驱动中缓冲，最后无参 Exec 提交，提交经过拦截器链
+func copyIn(ctx context.Context, tx *Begin, query string, columns int, rows iter.Seq[[]any]) (affected int64, err error) {
+	stmt, err := tx.Tx.PrepareContext(ctx, query)
+	if err != nil {
+		return
+	}
+	defer stmt.Close()
+	for row := range rows {
+		if err = checkBulkRow(row, columns); err != nil {
+			return
+		}
+		if _, err = stmt.ExecContext(ctx, row...); err != nil {
+			return
+		}
+		affected++
+	}
+	err = tx.run(ctx, tx.operation(query, nil), func(ctx context.Context, op *Operation) (err error) {
+		op.Result, err = stmt.ExecContext(ctx)
+		return
+	})
+	if err != nil {
+		return 0, err
+	}
+	return
+}
+
+func loadData(ctx context.Context, tx *Begin, table string, columns []string, rows iter.Seq[[]any]) (affected int64, err error) {
+	pr, pw := io.Pipe()
+	name := fmt.Sprintf("qiao_bulk_%d", bulkReaderSeq.Add(1))
+	mysql.RegisterReaderHandler(name, func() io.Reader { return pr })
+	defer mysql.DeregisterReaderHandler(name)
+	loadDataReaders.Store(name, pr)
+	defer loadDataReaders.Delete(name)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- writeLoadData(pw, len(columns), rows)
+	}()
+
+	query := fmt.Sprintf("LOAD DATA LOCAL INFILE 'Reader::%s' INTO TABLE %s CHARACTER SET utf8mb4 FIELDS TERMINATED BY '\\t' ESCAPED BY '\\\\' LINES TERMINATED BY '\\n' (%s)", name, table, strings.Join(columns, ","))
+	result, err := tx.ExecContext(ctx, query)
+	// 出错时关闭管道，结束写入协程；行数据错误优先返回
+	pr.CloseWithError(io.ErrClosedPipe)
+	if rowErr := <-done; rowErr != nil && !errors.Is(rowErr, io.ErrClosedPipe) {
+		return 0, rowErr
+	}
+	if err != nil {
+		return
+	}
+	return result.RowsAffected()
+}
+
+// 按 LOAD DATA 格式写入管道，出错时以该错误关闭管道
+func writeLoadData(pw *io.PipeWriter, columns int, rows iter.Seq[[]any]) (err error) {
+	defer func() { pw.CloseWithError(err) }()
+	var buf bytes.Buffer
+	for row := range rows {
+		if err = checkBulkRow(row, columns); err != nil {
+			return
+		}
+		buf.Reset()
+		for i, v := range row {
+			if i > 0 {
+				buf.WriteByte('\t')
+			}
+			if err = writeLoadDataValue(&buf, v); err != nil {
+				return
+			}
+		}
+		buf.WriteByte('\n')
+		if _, err = pw.Write(buf.Bytes()); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func checkBulkRow(row []any, columns int) error {
+	if len(row) != columns {
+		return fmt.Errorf("%w: row has %d values, want %d", ErrBulkRow, len(row), columns)
+	}
+	return nil
+}
+
+// 与 database/sql 绑定参数相同，解开 driver.Valuer 与指针，nil 指针为 NULL
+func loadDataValue(v any) (any, error) {
+	for v != nil {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return nil, nil
+		}
+		if valuer, ok := v.(driver.Valuer); ok {
+			val, err := valuer.Value()
+			if err != nil {
+				return nil, err
+			}
+			v = val
+			continue
+		}
+		if rv.Kind() != reflect.Pointer {
+			return v, nil
+		}
+		v = rv.Elem().Interface()
+	}
+	return nil, nil
+}
+
+// LOAD DATA 默认格式：\N 表示 NULL，反斜杠转义特殊字符
+func writeLoadDataValue(buf *bytes.Buffer, v any) error {
+	v, err := loadDataValue(v)
+	if err != nil {
+		return err
+	}
+	var s string
+	switch val := v.(type) {
+	case nil:
+		buf.WriteString(`\N`)
+		return nil
+	case string:
+		s = val
+	case []byte:
+		s = string(val)
+	case bool:
+		if val {
+			s = "1"
+		} else {
+			s = "0"
+		}
+	case time.Time:
+		s = val.Format("2006-01-02 15:04:05.999999")
+	default:
+		s = fmt.Sprint(val)
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case 0:
+			buf.WriteString(`\0`)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return nil
+}
+
+// 多行 insert 分批写入
+func bulkInsert(ctx context.Context, tx *Begin, table string, columns []string, rows iter.Seq[[]any]) (affected int64, err error) {
+	batch := max(bulkMaxParams/len(columns), 1)
+	head := fmt.Sprintf("INSERT INTO %s(%s)VALUES ", table, strings.Join(columns, ","))
+	value := "(" + Placeholders(len(columns)) + ")"
+
+	var n int
+	args := make([]any, 0, batch*len(columns))
+	flush := func() error {
+		if n == 0 {
+			return nil
+		}
+		result, err := tx.ExecContext(ctx, head+strings.Repeat(value+",", n-1)+value, args...)
+		if err != nil {
+			return err
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		affected += count
+		n, args = 0, args[:0]
+		return nil
+	}
+	for row := range rows {
+		if err = checkBulkRow(row, len(columns)); err != nil {
+			return 0, err
+		}
+		args = append(args, row...)
+		if n++; n == batch {
+			if err = flush(); err != nil {
+				return
+			}
+		}
+	}
+	if err = flush(); err != nil {
+		return
+	}
+	return
+}
@@ -0,0 +1,277 @@
+package DB
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/chris-liu-zh/qiao/tools"
+	mssql "github.com/denisenkom/go-mssqldb"
+)
+
+var ErrProcNotSupported = errors.New("stored procedure is not supported by this database type")
+
+const (
+	paramIn = iota
+	paramOut
+	paramInOut
+)
+
+type procParam struct {
+	name  string
+	value any
+	dest  any
+	mode  int
+}
+
+// Procedure 参数化存储过程调用，输入值全部以参数绑定
+type Procedure struct {
+	name     string
+	function bool
+	params   []procParam
+	status   mssql.ReturnStatus
+	mapper   *Mapper
+}
+
+/*
+Call 调用存储过程
+
+	mssql 以 RPC 方式调用，参数按名称绑定；
+	mysql 使用 CALL，输出参数通过会话变量读取；
+	pgsql 使用 CALL，Function 后使用 select * from 函数
+*/
+func (mapper *Mapper) Call(name string) *Procedure {
+	return &Procedure{name: name, mapper: mapper}
+}
+
+// Function pgsql 以函数方式调用，Out 参数对应返回列
+func (p *Procedure) Function() *Procedure {
+	p.function = true
+	return p
+}
+
+// In 输入参数
+func (p *Procedure) In(name string, value any) *Procedure {
+	p.params = append(p.params, procParam{name: procParamName(name), value: value, mode: paramIn})
+	return p
+}
+
+// Out 输出参数，dest 为接收值的指针
+func (p *Procedure) Out(name string, dest any) *Procedure {
+	p.params = append(p.params, procParam{name: procParamName(name), dest: dest, mode: paramOut})
+	return p
+}
+
+// InOut 输入输出参数，dest 指向的值作为输入，调用后写回输出值
+func (p *Procedure) InOut(name string, dest any) *Procedure {
+	p.params = append(p.params, procParam{name: procParamName(name), dest: dest, mode: paramInOut})
+	return p
+}
+
+// ReturnStatus mssql 存储过程返回值，Query 方式需在结果集关闭后读取
+func (p *Procedure) ReturnStatus() int {
+	return int(p.status)
+}
+
+func procParamName(name string) string {
+	return strings.TrimLeft(name, "@")
+}
+
+func (param procParam) input() any {
+	if param.mode == paramIn {
+		return param.value
+	}
+	v := reflect.ValueOf(param.dest)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return nil
+	}
+	return v.Elem().Interface()
+}
+
+// Exec 执行存储过程，输出参数写入 Out/InOut 的 dest
+func (p *Procedure) Exec() (err error) {
+	db := p.mapper.Write()
+	if db == nil {
+		return ErrNoConn
+	}
+	for _, param := range p.params {
+		if param.mode != paramIn && reflect.ValueOf(param.dest).Kind() != reflect.Pointer {
+			return ErrNotPtr
+		}
+	}
+	ctx := p.mapper.context()
+	switch db.Conf.Type {
+	case "mssql":
+		args := p.mssqlArgs()
+		p.complete(p.name, args)
+		_, err = db.ExecContext(ctx, p.name, args...)
+	case "mysql":
+		if err = p.execMysql(ctx, db); err == nil || !db.checkOpError(err) {
+			return
+		}
+		if db = GetNewPool(db.Conf.Role); db == nil {
+			return ErrNoConn
+		}
+		err = p.execMysql(ctx, db)
+	case "pgsql":
+		query, args, dests := p.pgsqlCall()
+		p.complete(query, args)
+		if len(dests) == 0 {
+			_, err = db.ExecContext(ctx, query, args...)
+			return
+		}
+		err = db.scanRow(ctx, "Call", query, args, dests...)
+	default:
+		return ErrProcNotSupported
+	}
+	return
+}
+
+/*
+Query 执行存储过程并返回结果集
+
+	mssql 的输出参数和返回值在结果集读取完并关闭后才会写入；
+	mysql 不支持在此方式下读取输出参数
+*/
+func (p *Procedure) Query() (rows *sql.Rows, err error) {
+	db := p.mapper.Write()
+	if db == nil {
+		return nil, ErrNoConn
+	}
+	ctx := p.mapper.context()
+	switch db.Conf.Type {
+	case "mssql":
+		args := p.mssqlArgs()
+		p.complete(p.name, args)
+		return db.QueryContext(ctx, p.name, args...)
+	case "mysql":
+		var holders []string
+		var args []any
+		for _, param := range p.params {
+			if param.mode != paramIn {
+				return nil, ErrProcNotSupported
+			}
+			holders = append(holders, "?")
+			args = append(args, param.value)
+		}
+		query := fmt.Sprintf("CALL %s(%s)", p.name, strings.Join(holders, ","))
+		p.complete(query, args)
+		return db.QueryContext(ctx, query, args...)
+	case "pgsql":
+		query, args, _ := p.pgsqlCall()
+		p.complete(query, args)
+		return db.QueryContext(ctx, query, args...)
+	}
+	return nil, ErrProcNotSupported
+}
+
+func (p *Procedure) complete(query string, args []any) {
+	p.mapper.Complete.Sql = query
+	p.mapper.Complete.Args = args
+	p.mapper.debug("Call")
+}
+
+func (p *Procedure) mssqlArgs() []any {
+	args := make([]any, 0, len(p.params)+1)
+	for _, param := range p.params {
+		switch param.mode {
+		case paramIn:
+			args = append(args, sql.Named(param.name, param.value))
+		case paramOut:
+			args = append(args, sql.Named(param.name, sql.Out{Dest: param.dest}))
+		case paramInOut:
+			args = append(args, sql.Named(param.name, sql.Out{Dest: param.dest, In: true}))
+		}
+	}
+	return append(args, &p.status)
+}
+
+// mysql 输出参数使用同一连接上的会话变量传递，语句不重试
+func (p *Procedure) execMysql(ctx context.Context, db *ConnDB) (err error) {
+	conn, err := db.DBFunc.Conn.Conn(ctx)
+	if err != nil {
+		return
+	}
+	defer tools.DeferErr(&err, conn.Close)
+
+	var holders, outs []string
+	var args, dests []any
+	for _, param := range p.params {
+		variable := "@" + param.name
+		switch param.mode {
+		case paramIn:
+			holders = append(holders, "?")
+			args = append(args, param.value)
+			continue
+		case paramInOut:
+			if err = db.connExec(ctx, conn, "SET "+variable+" = ?", []any{param.input()}); err != nil {
+				return
+			}
+		}
+		holders = append(holders, variable)
+		outs = append(outs, variable)
+		dests = append(dests, param.dest)
+	}
+	query := fmt.Sprintf("CALL %s(%s)", p.name, strings.Join(holders, ","))
+	p.complete(query, args)
+	if err = db.connExec(ctx, conn, query, args); err != nil {
+		return
+	}
+	if len(outs) == 0 {
+		return
+	}
+	query = "SELECT " + strings.Join(outs, ",")
+	err = intercept(ctx, db.operation("queryRow", query, nil), func(ctx context.Context, op *Operation) error {
+		return conn.QueryRowContext(ctx, op.Sql, op.Args...).Scan(dests...)
+	})
+	if err != nil {
+		db.log("Call error", query).logERROR(err)
+	}
+	return
+}
+
+// 在固定连接上经过拦截器链执行
+func (db *ConnDB) connExec(ctx context.Context, conn *sql.Conn, query string, args []any) error {
+	args = handleNull(args...)
+	err := intercept(ctx, db.operation("exec", query, args), func(ctx context.Context, op *Operation) (err error) {
+		op.Result, err = conn.ExecContext(ctx, op.Sql, op.Args...)
+		return
+	})
+	if err != nil {
+		db.log("Call error", query, args...).logERROR(err)
+	}
+	return err
+}
+
+/*
+pgsql 调用语句
+
+	存储过程：CALL name(?, NULL, ?)，OUT 参数传 NULL，返回行为 INOUT/OUT 的值
+	函数：select * from name(?, ?)，OUT 参数不传，返回列为 INOUT/OUT 的值
+*/
+func (p *Procedure) pgsqlCall() (query string, args, dests []any) {
+	var holders []string
+	for _, param := range p.params {
+		switch param.mode {
+		case paramIn:
+			holders = append(holders, "?")
+			args = append(args, param.value)
+		case paramOut:
+			if !p.function {
+				holders = append(holders, "NULL")
+			}
+			dests = append(dests, param.dest)
+		case paramInOut:
+			holders = append(holders, "?")
+			args = append(args, param.input())
+			dests = append(dests, param.dest)
+		}
+	}
+	if p.function {
+		return fmt.Sprintf("select * from %s(%s)", p.name, strings.Join(holders, ",")), args, dests
+	}
+	return fmt.Sprintf("CALL %s(%s)", p.name, strings.Join(holders, ",")), args, dests
+}
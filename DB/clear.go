@@ -11,16 +11,25 @@ import (
 	"context"
 	"errors"
 	"net"
+	"sync"
 	"time"
 )
 
+// 进行中的重连，Stop 时等待其退出
+var reconnects sync.WaitGroup
+
 // 检测数据库错误是否为网络错误,并使用重连机制
 func (db *ConnDB) checkOpError(err error) bool {
 	var opError *net.OpError
 	if errors.As(err, &opError) {
 		//网络错误，断开连接
 		db.IsClose = true
-		go db.reconnect() //异步重连
+		db.purgeStmt()
+		reconnects.Add(1)
+		go func() { //异步重连
+			defer reconnects.Done()
+			db.reconnect()
+		}()
 		return true
 	}
 	return false
@@ -36,7 +45,8 @@ func (db *ConnDB) reconnect() {
 	db.RetryIng = true
 	defer func() { db.RetryIng = false }()
 	//TODO 重连机制
-	for range Pool.ReconnectNum {
+	_, reconnectNum, reconnectInterval := poolOptions()
+	for range reconnectNum {
 		if !db.IsClose {
 			return
 		}
@@ -44,7 +54,12 @@ func (db *ConnDB) reconnect() {
 			db.log("reconnect success", db.Conf.Dsn).logINFO()
 			return
 		}
-		time.Sleep(Pool.ReconnectInterval)
+		select {
+		case <-db.closed.Done():
+			// 连接已从连接池移除并关闭
+			return
+		case <-time.After(reconnectInterval):
+		}
 	}
 }
 
@@ -53,6 +68,7 @@ func (db *ConnDB) checkOnline() bool {
 	defer cancel()
 	if err := db.DBFunc.Conn.PingContext(ctx); err != nil {
 		db.IsClose = true
+		db.purgeStmt()
 		db.log("ping error", "").logERROR(err)
 		return false
 	}
@@ -7,6 +7,17 @@
  */
 package DB
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/chris-liu-zh/qiao/tools"
+)
+
 /*
 获取字段
 */
@@ -44,3 +55,239 @@ func ReadOnlyField(f []string) bool {
 	}
 	return true
 }
+
+// 可读字段及其在结构体中的位置
+type readColumn struct {
+	column  string
+	index   []int
+	field   reflect.StructField
+	json    bool
+	encrypt bool
+}
+
+/*
+获取结构体可读字段，嵌套或匿名嵌入的结构体展开为其字段
+
+	@prefix string；--表别名，嵌套结构体字段的 db 标签作为其内部字段的别名，
+	如 Customer Customer `db:"c"` 的字段查询为 c.id,c.name；已写明 alias.column 的字段不再加前缀
+*/
+func readColumns(t reflect.Type, prefix string, index []int) (columns []readColumn) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() || isRelation(field) {
+			continue
+		}
+		tags := strings.Split(field.Tag.Get("db"), ";")
+		if !ReadOnlyField(tags) {
+			continue
+		}
+		column := getColumn(tags)
+		fieldIndex := append(slices.Clone(index), i)
+		jsonField := tagOption(tags, "json")
+		if !jsonField && isStructRow(field.Type) {
+			alias := column
+			if alias == "" && !field.Anonymous {
+				alias = tools.CamelCaseToUdnderscore(field.Name)
+			}
+			if alias == "" {
+				alias = prefix
+			}
+			columns = append(columns, readColumns(field.Type, alias, fieldIndex)...)
+			continue
+		}
+		if column == "" {
+			column = tools.CamelCaseToUdnderscore(field.Name)
+		}
+		if prefix != "" && !strings.Contains(column, ".") {
+			column = prefix + "." + column
+		}
+		columns = append(columns, readColumn{column: column, index: fieldIndex, field: field, json: jsonField, encrypt: tagOption(tags, "encrypt")})
+	}
+	return
+}
+
+// 查询字段列表
+func selectFields(t reflect.Type) string {
+	var fields []string
+	for _, c := range readColumns(t, "", nil) {
+		fields = append(fields, c.column)
+	}
+	return strings.Join(fields, ",")
+}
+
+/*
+按结果集列名对应结构体字段，返回与结果列一一对应的字段
+
+	列名与字段列名相同，或与 alias.column、column as name 的最后一段相同时对应，重名时按顺序对应；
+	按名称对应不上的列，取同一位置上未被对应的字段（原生 Query 如 count(*) as cnt 按位置读入），
+	仍没有对应字段时返回 ErrUnmatchedColumn；Field("id") 等列少于字段时只填充对应字段
+*/
+func matchColumns(t reflect.Type, names []string) ([]readColumn, error) {
+	fields := readColumns(t, "", nil)
+	used := make([]bool, len(fields))
+	matched := make([]readColumn, len(names))
+	for i, name := range names {
+		for j, f := range fields {
+			if !used[j] && columnMatch(f.column, name) {
+				used[j], matched[i] = true, f
+				break
+			}
+		}
+	}
+	for i, name := range names {
+		if matched[i].index != nil {
+			continue
+		}
+		if i >= len(fields) || used[i] {
+			return nil, fmt.Errorf("%w: %s", ErrUnmatchedColumn, name)
+		}
+		used[i], matched[i] = true, fields[i]
+	}
+	return matched, nil
+}
+
+func columnMatch(column, name string) bool {
+	if strings.EqualFold(column, name) {
+		return true
+	}
+	if i := strings.LastIndexByte(column, ' '); i >= 0 {
+		column = column[i+1:]
+	}
+	if i := strings.LastIndexByte(column, '.'); i >= 0 {
+		column = column[i+1:]
+	}
+	return strings.EqualFold(column, name)
+}
+
+// 获取结构体可读字段的指针，json / encrypt 字段读取后转换
+func fieldPointers(item reflect.Value, columns []readColumn) []any {
+	pointer := make([]any, len(columns))
+	for i, c := range columns {
+		if c.json || c.encrypt {
+			pointer[i] = fieldScanner{field: item.FieldByIndex(c.index), json: c.json, encrypt: c.encrypt}
+			continue
+		}
+		pointer[i] = item.FieldByIndex(c.index).Addr().Interface()
+	}
+	return pointer
+}
+
+// 标签选项，如 db:"id_card;encrypt" 中的 encrypt
+func tagOption(tags []string, option string) bool {
+	return slices.Contains(tags[min(1, len(tags)):], option)
+}
+
+// 标签选项的值，如 db:"id_card;encrypt;blind:id_card_bidx" 中 blind 的值
+func tagValue(tags []string, option string) string {
+	for _, v := range tags[min(1, len(tags)):] {
+		if val, ok := strings.CutPrefix(v, option+":"); ok {
+			return val
+		}
+	}
+	return ""
+}
+
+/*
+写入时字段的绑定值：json 序列化，encrypt 加密
+
+	@blind string；--encrypt 字段的盲索引列，为空时不写入盲索引
+*/
+func writeValue(tags []string, v reflect.Value) (val any, blind string, index string, err error) {
+	jsonField, encrypt := tagOption(tags, "json"), tagOption(tags, "encrypt")
+	if !jsonField && !encrypt {
+		return v.Interface(), "", "", nil
+	}
+	// nil 的 map / slice / 指针存为 NULL，而不是 "null"
+	if jsonField && nilable(v) && v.IsNil() {
+		return nil, "", "", nil
+	}
+	var plain []byte
+	switch {
+	case jsonField:
+		if plain, err = json.Marshal(v.Interface()); err != nil {
+			return
+		}
+		if !encrypt {
+			return string(plain), "", "", nil
+		}
+	case v.Kind() == reflect.Pointer:
+		v = v.Elem()
+		fallthrough
+	default:
+		switch {
+		case v.Kind() == reflect.String:
+			plain = []byte(v.String())
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			plain = v.Bytes()
+		default:
+			return nil, "", "", fmt.Errorf("encrypt field must be string or []byte, got %s", v.Type())
+		}
+	}
+	blind = tagValue(tags, "blind")
+	if val, index, err = encryptValue(plain, blind != ""); err != nil {
+		return nil, "", "", err
+	}
+	return val, blind, index, nil
+}
+
+func nilable(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Pointer, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// 读取 json / encrypt 字段时先解密再反序列化，写入结构体字段
+type fieldScanner struct {
+	field   reflect.Value
+	json    bool
+	encrypt bool
+}
+
+func (f fieldScanner) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		f.field.SetZero()
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported Scan, storing driver.Value type %T into json/encrypt field %s", src, f.field.Type())
+	}
+	if f.encrypt && !f.json {
+		t := f.field.Type()
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.String && (t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.Uint8) {
+			return fmt.Errorf("encrypt field must be string or []byte, got %s", f.field.Type())
+		}
+	}
+	if f.encrypt {
+		plain, err := decryptValue(string(data))
+		if err != nil {
+			return err
+		}
+		data = plain
+	}
+	if f.json {
+		// 先清空，避免与上一行共用 map / slice
+		f.field.SetZero()
+		return json.Unmarshal(data, f.field.Addr().Interface())
+	}
+	field := f.field
+	if field.Kind() == reflect.Pointer {
+		field.Set(reflect.New(field.Type().Elem()))
+		field = field.Elem()
+	}
+	if field.Kind() == reflect.String {
+		field.SetString(string(data))
+		return nil
+	}
+	field.SetBytes(bytes.Clone(data))
+	return nil
+}
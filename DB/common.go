@@ -1,7 +1,7 @@
 package DB
 
 import (
-	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"os"
 	"reflect"
@@ -40,6 +40,15 @@ sql模板拼接
 	@str struct;	--sqlstr
 */
 func (mapper *Mapper) getSql() (sql string, err error) {
+	if mapper.err != nil {
+		return "", mapper.err
+	}
+	if mapper.statement != "" {
+		return mapper.countSql(mapper.statement), nil
+	}
+	if err = mapper.tenantScope(); err != nil {
+		return
+	}
 	if mapper.Debris.field == "" {
 		mapper.Debris.field = "*"
 	}
@@ -50,7 +59,32 @@ func (mapper *Mapper) getSql() (sql string, err error) {
 	if err != nil {
 		return
 	}
-	return os.Expand(mapper.SqlTpl, func(k string) string { return sqlMap[k] }), nil
+	if mapper.Debris.alias != "" {
+		sqlMap["table"] += " " + mapper.Debris.alias
+	}
+	union := mapper.compose.union
+	if union != "" && mapper.setOrdered() {
+		// 集合运算整体排序、分页：先组成集合，外层再套用原模板
+		tpl := Select
+		if mapper.SqlTpl == SelectJoin {
+			tpl = SelectJoin
+		}
+		order := sqlMap["order"]
+		sqlMap["order"] = ""
+		body := strings.TrimSpace(os.Expand(tpl, func(k string) string { return sqlMap[k] })) + union
+		sqlMap = map[string]string{"field": "*", "join_field": "*", "table": "(" + body + ") u", "order": order}
+		union = ""
+	}
+	return mapper.composeSql(os.Expand(mapper.SqlTpl, func(k string) string { return sqlMap[k] }), union), nil
+}
+
+// 查询带排序或分页
+func (mapper *Mapper) setOrdered() bool {
+	switch mapper.SqlTpl {
+	case Insert, Update, Del:
+		return false
+	}
+	return mapper.Debris.order != "" || len(mapper.compose.limitArgs) > 0 || len(mapper.compose.topArgs) > 0
 }
 
 /*
@@ -102,55 +136,26 @@ func Placeholders(n int) string {
 	return b.String()
 }
 
-func handleNull(args ...any) (anydata []any) {
-	var val any
-	for _, value := range args {
-		switch value.(type) {
-		case sql.NullBool:
-			if b, ok := (value).(sql.NullBool); ok {
-				val = b.Bool
-			}
-			anydata = append(anydata, val)
-		case sql.NullString:
-			if b, ok := (value).(sql.NullString); ok {
-				val = b.String
-			}
-			anydata = append(anydata, val)
-		case sql.NullByte:
-			if b, ok := (value).(sql.NullByte); ok {
-				val = b.Byte
-			}
-			anydata = append(anydata, val)
-		case sql.NullFloat64:
-			if b, ok := (value).(sql.NullFloat64); ok {
-				val = b.Float64
-			}
-			anydata = append(anydata, val)
-		case sql.NullInt64:
-			if b, ok := (value).(sql.NullInt64); ok {
-				val = b.Int64
-			}
-			anydata = append(anydata, val)
-		case sql.NullInt32:
-			if b, ok := (value).(sql.NullInt32); ok {
-				val = b.Int32
-			}
-			anydata = append(anydata, val)
-		case sql.NullInt16:
-			if b, ok := (value).(sql.NullInt16); ok {
-				val = b.Int16
-			}
-			anydata = append(anydata, val)
-		case sql.NullTime:
-			if b, ok := (value).(sql.NullTime); ok {
-				val = b.Time
+/*
+参数转为驱动值，sql.NullString / Null[T] 等 Valid 为 false 时写入 NULL
+
+	driver.Valuer 返回错误时保留原值，由 database/sql 报告错误
+*/
+func handleNull(args ...any) []any {
+	anydata := make([]any, len(args))
+	for i, value := range args {
+		anydata[i] = value
+		if rv := reflect.ValueOf(value); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			anydata[i] = nil
+			continue
+		}
+		if v, ok := value.(driver.Valuer); ok {
+			if val, err := v.Value(); err == nil {
+				anydata[i] = val
 			}
-			anydata = append(anydata, val)
-		default:
-			anydata = append(anydata, value)
 		}
 	}
-	return
+	return anydata
 }
 
 func (mapper *Mapper) log(msg string) *sqlLog {
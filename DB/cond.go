@@ -0,0 +1,224 @@
+package DB
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Cond 条件表达式，可直接传入 Find / Update / Del
+type Cond interface {
+	Build() (sql string, args []any)
+}
+
+type expr struct {
+	sql  string
+	args []any
+}
+
+func (e expr) Build() (string, []any) {
+	return e.sql, e.args
+}
+
+// 构造时出错的条件，Find / Having 时设置 mapper.err；单独 Build 时为 1 = 0，不会匹配任何行
+type badCond struct {
+	err error
+}
+
+func (b badCond) Build() (string, []any) {
+	return "1 = 0", nil
+}
+
+// 条件树中第一个构造错误
+func condError(cond Cond) error {
+	switch c := cond.(type) {
+	case badCond:
+		return c.err
+	case group:
+		for _, sub := range c.conds {
+			if err := condError(sub); err != nil {
+				return err
+			}
+		}
+	case not:
+		return condError(c.cond)
+	}
+	return nil
+}
+
+type group struct {
+	op    string
+	conds []Cond
+}
+
+func (g group) Build() (string, []any) {
+	var parts []string
+	var args []any
+	var wrap []bool
+	for _, c := range g.conds {
+		if c == nil {
+			continue
+		}
+		s, a := c.Build()
+		if s == "" {
+			continue
+		}
+		_, isNot := c.(not)
+		parts = append(parts, s)
+		args = append(args, a...)
+		wrap = append(wrap, !isNot)
+	}
+	if len(parts) == 1 {
+		return parts[0], args
+	}
+	// 多个子条件时逐个加括号，避免 Raw("a = 1 or b = 2") 与 and / or 的优先级混淆；not (...) 已带括号
+	for i := range parts {
+		if wrap[i] {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	return strings.Join(parts, " "+g.op+" "), args
+}
+
+type not struct {
+	cond Cond
+}
+
+func (n not) Build() (string, []any) {
+	if n.cond == nil {
+		return "", nil
+	}
+	s, args := n.cond.Build()
+	if s == "" {
+		return "", nil
+	}
+	return fmt.Sprintf("not (%s)", s), args
+}
+
+// Raw 原生条件
+func Raw(sql string, args ...any) Cond {
+	return expr{sql: sql, args: args}
+}
+
+// Eq column = ?
+func Eq(column string, val any) Cond {
+	return compare(column, "=", val)
+}
+
+// Ne column <> ?
+func Ne(column string, val any) Cond {
+	return compare(column, "<>", val)
+}
+
+// Gt column > ?
+func Gt(column string, val any) Cond {
+	return compare(column, ">", val)
+}
+
+// Ge column >= ?
+func Ge(column string, val any) Cond {
+	return compare(column, ">=", val)
+}
+
+// Lt column < ?
+func Lt(column string, val any) Cond {
+	return compare(column, "<", val)
+}
+
+// Le column <= ?
+func Le(column string, val any) Cond {
+	return compare(column, "<=", val)
+}
+
+// val 为 *Mapper 时作为子查询
+func compare(column, op string, val any) Cond {
+	if sub, ok := val.(*Mapper); ok {
+		s, args := sub.subquery()
+		return expr{sql: fmt.Sprintf("%s %s (%s)", column, op, s), args: args}
+	}
+	return expr{sql: fmt.Sprintf("%s %s ?", column, op), args: []any{val}}
+}
+
+/*
+In column in (?,?...)
+
+	@values slice 或多个值；--空集合时条件恒为假；单个 *Mapper 时作为子查询
+*/
+func In(column string, values ...any) Cond {
+	return in(column, "in", "1 = 0", values)
+}
+
+/*
+NotIn column not in (?,?...)
+
+	@values slice 或多个值；--空集合时条件恒为真；单个 *Mapper 时作为子查询
+*/
+func NotIn(column string, values ...any) Cond {
+	return in(column, "not in", "1 = 1", values)
+}
+
+func in(column, op, empty string, values []any) Cond {
+	if len(values) == 1 {
+		if sub, ok := values[0].(*Mapper); ok {
+			s, args := sub.subquery()
+			return expr{sql: fmt.Sprintf("%s %s (%s)", column, op, s), args: args}
+		}
+		if v := reflect.ValueOf(values[0]); v.Kind() == reflect.Slice {
+			values = make([]any, v.Len())
+			for i := range v.Len() {
+				values[i] = v.Index(i).Interface()
+			}
+		}
+	}
+	if len(values) == 0 {
+		return expr{sql: empty}
+	}
+	return expr{sql: fmt.Sprintf("%s %s (%s)", column, op, Placeholders(len(values))), args: values}
+}
+
+// Between column between ? and ?
+func Between(column string, start, end any) Cond {
+	return expr{sql: column + " between ? and ?", args: []any{start, end}}
+}
+
+// Like column like ?，pattern 原样绑定，通配符由调用方决定
+func Like(column, pattern string) Cond {
+	return expr{sql: column + " like ?", args: []any{pattern}}
+}
+
+// IsNull column is null
+func IsNull(column string) Cond {
+	return expr{sql: column + " is null"}
+}
+
+// IsNotNull column is not null
+func IsNotNull(column string) Cond {
+	return expr{sql: column + " is not null"}
+}
+
+// And 以 and 连接条件，嵌套分组自动加括号
+func And(conds ...Cond) Cond {
+	return group{op: "and", conds: conds}
+}
+
+// Or 以 or 连接条件，嵌套分组自动加括号
+func Or(conds ...Cond) Cond {
+	return group{op: "or", conds: conds}
+}
+
+// Not 条件取反
+func Not(cond Cond) Cond {
+	return not{cond: cond}
+}
+
+func (mapper *Mapper) whereCond(cond Cond) *Mapper {
+	if cond == nil {
+		return mapper
+	}
+	if err := condError(cond); err != nil {
+		mapper.err = err
+		return mapper
+	}
+	s, args := cond.Build()
+	return mapper.where(s, args...)
+}
@@ -0,0 +1,227 @@
+package DB
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/chris-liu-zh/qiao/tools"
+	"gopkg.in/yaml.v3"
+)
+
+// MasterKeyEnv 解密配置文件中密码的主密钥所在环境变量
+const MasterKeyEnv = "QIAO_MASTER_KEY"
+
+/*
+FileConfig 数据库配置文件
+
+	{
+		"SwitchRole": true,
+		"ReconnectNum": 3,
+		"ReconnectInterval": "5s",
+		"Databases": [{"ID": 1, "Type": "pgsql", "Role": "master", "Host": "${PG_HOST}", "Port": 5432, "Pwd": "AES(...)"}]
+	}
+*/
+type FileConfig struct {
+	SwitchRole        bool     `json:"SwitchRole"`
+	ReconnectNum      int      `json:"ReconnectNum"`
+	ReconnectInterval string   `json:"ReconnectInterval"`
+	Databases         []Config `json:"Databases"`
+	interval          time.Duration
+}
+
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+/*
+LoadConfig 读取数据库配置文件
+
+	@path string；--按扩展名解析 .json / .yaml / .yml / .toml
+	解析后字符串字段中的 ${NAME} 替换为环境变量，${NAME:-默认值} 在变量未设置时使用默认值，
+	变量值原样使用，不会改变文件结构；数字、布尔字段不做替换；
+	Pwd 写为 AES(密文) 或 SM4(密文) 时使用环境变量 QIAO_MASTER_KEY 中的主密钥解密，
+	密文由 tools.SealAES / tools.SealSM4 生成
+*/
+func LoadConfig(path string) (fc *FileConfig, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	// yaml / toml 先解析为通用结构再转为 json，统一使用 Config 的 json 标签
+	var raw map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+	case ".yaml", ".yml":
+		if err = yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		if err = toml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported config format %q", path, ext)
+	}
+	if raw != nil {
+		if data, err = json.Marshal(raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	fc = &FileConfig{}
+	if err = json.Unmarshal(data, fc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var missing []string
+	expandEnv(reflect.ValueOf(fc), &missing)
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%s: environment variable not set: %s", path, strings.Join(missing, ", "))
+	}
+	if err = fc.decryptPwd(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err = fc.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return fc, nil
+}
+
+// 替换结构体中字符串字段的环境变量
+func expandEnv(v reflect.Value, missing *[]string) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		expandEnv(v.Elem(), missing)
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if v.Field(i).CanSet() {
+				expandEnv(v.Field(i), missing)
+			}
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			expandEnv(v.Index(i), missing)
+		}
+	case reflect.String:
+		v.SetString(envPattern.ReplaceAllStringFunc(v.String(), func(m string) string {
+			sub := envPattern.FindStringSubmatch(m)
+			if val, ok := os.LookupEnv(sub[1]); ok {
+				return val
+			}
+			if sub[2] != "" {
+				return sub[3]
+			}
+			*missing = append(*missing, sub[1])
+			return ""
+		}))
+	}
+}
+
+func (fc *FileConfig) decryptPwd() error {
+	var errs []error
+	for i := range fc.Databases {
+		conf := &fc.Databases[i]
+		var decrypt func(string, string) (string, error)
+		var text string
+		if s, ok := strings.CutPrefix(conf.Pwd, "AES("); ok && strings.HasSuffix(s, ")") {
+			decrypt, text = tools.OpenAES, strings.TrimSuffix(s, ")")
+		} else if s, ok := strings.CutPrefix(conf.Pwd, "SM4("); ok && strings.HasSuffix(s, ")") {
+			decrypt, text = tools.OpenSM4, strings.TrimSuffix(s, ")")
+		} else {
+			continue
+		}
+		key := os.Getenv(MasterKeyEnv)
+		if key == "" {
+			errs = append(errs, fmt.Errorf("%s: encrypted Pwd requires %s", conf.entry(i), MasterKeyEnv))
+			continue
+		}
+		pwd, err := decrypt(text, key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: decrypt Pwd: %w", conf.entry(i), err))
+			continue
+		}
+		conf.Pwd = pwd
+	}
+	return errors.Join(errs...)
+}
+
+// Validate 校验配置，错误信息包含出错的配置项
+func (fc *FileConfig) Validate() error {
+	var errs []error
+	if fc.ReconnectInterval != "" {
+		d, err := time.ParseDuration(fc.ReconnectInterval)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ReconnectInterval: %w", err))
+		}
+		fc.interval = d
+	}
+	if len(fc.Databases) == 0 {
+		errs = append(errs, errors.New("Databases: no database configured"))
+	}
+	ids := make(map[int]int)
+	for i, conf := range fc.Databases {
+		if j, ok := ids[conf.ID]; ok {
+			errs = append(errs, fmt.Errorf("%s: duplicate ID %d with Databases[%d]", conf.entry(i), conf.ID, j))
+		}
+		ids[conf.ID] = i
+		if err := conf.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", conf.entry(i), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate 校验单个数据库配置
+func (conf Config) Validate() error {
+	var errs []error
+	switch conf.Type {
+	case "pgsql", "mysql", "mssql":
+		if conf.Dsn == "" && conf.Host == "" {
+			errs = append(errs, errors.New("Host or Dsn is required"))
+		}
+		if conf.Port < 0 || conf.Port > 65535 {
+			errs = append(errs, fmt.Errorf("invalid Port %d", conf.Port))
+		}
+	case "sqlite":
+		if conf.Dsn == "" {
+			errs = append(errs, errors.New("Dsn is required for sqlite"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unsupported Type %q, want pgsql, mysql, mssql or sqlite", conf.Type))
+	}
+	switch conf.Role {
+	case "", "master", "slave", "alone":
+	default:
+		errs = append(errs, fmt.Errorf("unsupported Role %q, want master, slave or alone", conf.Role))
+	}
+	if conf.MaxIdleTime != "" {
+		if _, err := time.ParseDuration(conf.MaxIdleTime); err != nil {
+			errs = append(errs, fmt.Errorf("MaxIdleTime: %w", err))
+		}
+	}
+	if conf.MaxIdle < 0 || conf.MaxOpen < 0 || conf.StmtCache < 0 {
+		errs = append(errs, errors.New("MaxIdle, MaxOpen and StmtCache must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// 配置项名称，用于错误信息
+func (conf Config) entry(i int) string {
+	if conf.Title != "" {
+		return fmt.Sprintf("Databases[%d] (ID %d, %s)", i, conf.ID, conf.Title)
+	}
+	return fmt.Sprintf("Databases[%d] (ID %d)", i, conf.ID)
+}
+
+// InitDBFile 读取配置文件并初始化连接池
+func InitDBFile(path string) error {
+	fc, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	return InitDB(fc.SwitchRole, fc.ReconnectNum, fc.interval, fc.Databases...)
+}
@@ -0,0 +1,214 @@
+package DB
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+	sqlite "github.com/glebarez/go-sqlite"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// 与驱动无关的错误类型，使用 errors.Is 判断
+var (
+	ErrDuplicateKey = errors.New("duplicate key")
+	ErrForeignKey   = errors.New("foreign key violation")
+	ErrNotNull      = errors.New("not null violation")
+	ErrCheck        = errors.New("check constraint violation")
+	ErrDeadlock     = errors.New("deadlock or serialization failure")
+	ErrTimeout      = errors.New("lock or statement timeout")
+)
+
+/*
+DBError 归类后的数据库错误，同时包装错误类型与驱动原始错误
+
+	errors.Is(err, DB.ErrDuplicateKey) 判断类型，errors.As(err, &pqErr) 仍可取得驱动错误；
+	经 qiao.Err 包装后同样适用
+*/
+type DBError struct {
+	Kind       error  // ErrDuplicateKey / ErrForeignKey / ...
+	Dialect    string // 数据库类型
+	Code       string // 驱动错误码
+	Table      string // 表名，驱动未提供时为空
+	Constraint string // 约束或索引名，驱动未提供时为空
+	Column     string // 列名，驱动未提供时为空
+	Err        error  // 驱动原始错误
+}
+
+func (e *DBError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DBError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// AsDBError 取出归类后的数据库错误，不是时返回 nil
+func AsDBError(err error) *DBError {
+	var e *DBError
+	if errors.As(err, &e) {
+		return e
+	}
+	return nil
+}
+
+var (
+	quotedName   = regexp.MustCompile("['\"`]([^'\"`]+)['\"`]")
+	mysqlFKName  = regexp.MustCompile("CONSTRAINT `([^`]+)` FOREIGN KEY \\(`([^`]+)`\\)")
+	mssqlObject  = regexp.MustCompile(`(?:object|table) ['"]([^'"]+)['"]`)
+	mssqlColumn  = regexp.MustCompile(`column '([^']+)'`)
+	mssqlConName = regexp.MustCompile(`constraint ['"]([^'"]+)['"]`)
+	mssqlIndex   = regexp.MustCompile(`unique index '([^']+)'`)
+)
+
+// 将驱动错误归类为 DBError，无法归类时原样返回
+func classifyError(dialect string, err error) error {
+	if err == nil || AsDBError(err) != nil {
+		return err
+	}
+	e := &DBError{Dialect: dialect, Err: err}
+	var (
+		myErr *mysql.MySQLError
+		pqErr *pq.Error
+		msErr mssql.Error
+		sqErr *sqlite.Error
+	)
+	switch {
+	case errors.As(err, &myErr):
+		e.Code = strconv.Itoa(int(myErr.Number))
+		classifyMysql(e, myErr)
+	case errors.As(err, &pqErr):
+		e.Code, e.Table, e.Constraint, e.Column = string(pqErr.Code), pqErr.Table, pqErr.Constraint, pqErr.Column
+		switch pqErr.Code {
+		case "23505":
+			e.Kind = ErrDuplicateKey
+		case "23503":
+			e.Kind = ErrForeignKey
+		case "23502":
+			e.Kind = ErrNotNull
+		case "23514":
+			e.Kind = ErrCheck
+		case "40001", "40P01":
+			e.Kind = ErrDeadlock
+		case "55P03", "57014":
+			e.Kind = ErrTimeout
+		}
+	case errors.As(err, &msErr):
+		e.Code = strconv.Itoa(int(msErr.Number))
+		classifyMssql(e, msErr)
+	case errors.As(err, &sqErr):
+		e.Code = strconv.Itoa(sqErr.Code())
+		classifySqlite(e, sqErr)
+	case errors.Is(err, context.DeadlineExceeded):
+		e.Kind = ErrTimeout
+	}
+	if e.Kind == nil {
+		return err
+	}
+	return e
+}
+
+func classifyMysql(e *DBError, err *mysql.MySQLError) {
+	switch err.Number {
+	case 1062, 1586:
+		// Duplicate entry 'a' for key 'item.name'
+		e.Kind = ErrDuplicateKey
+		if m := quotedName.FindAllStringSubmatch(err.Message, -1); len(m) > 1 {
+			e.Constraint = m[len(m)-1][1]
+			if table, key, ok := strings.Cut(e.Constraint, "."); ok {
+				e.Table, e.Constraint = table, key
+			}
+		}
+	case 1451, 1452, 1216, 1217:
+		// ... (`db`.`item`, CONSTRAINT `fk_item_pid` FOREIGN KEY (`pid`) REFERENCES ...)
+		e.Kind = ErrForeignKey
+		if m := mysqlFKName.FindStringSubmatch(err.Message); m != nil {
+			e.Constraint, e.Column = m[1], m[2]
+		}
+	case 1048, 1364:
+		// Column 'name' cannot be null / Field 'name' doesn't have a default value
+		e.Kind = ErrNotNull
+		if m := quotedName.FindStringSubmatch(err.Message); m != nil {
+			e.Column = m[1]
+		}
+	case 3819:
+		// Check constraint 'chk_n' is violated.
+		e.Kind = ErrCheck
+		if m := quotedName.FindStringSubmatch(err.Message); m != nil {
+			e.Constraint = m[1]
+		}
+	case 1213:
+		e.Kind = ErrDeadlock
+	case 1205, 3024:
+		e.Kind = ErrTimeout
+	}
+}
+
+func classifyMssql(e *DBError, err mssql.Error) {
+	if m := mssqlObject.FindStringSubmatch(err.Message); m != nil {
+		e.Table = m[1]
+	}
+	if m := mssqlColumn.FindStringSubmatch(err.Message); m != nil {
+		e.Column = m[1]
+	}
+	if m := mssqlConName.FindStringSubmatch(err.Message); m != nil {
+		e.Constraint = m[1]
+	} else if m := mssqlIndex.FindStringSubmatch(err.Message); m != nil {
+		e.Constraint = m[1]
+	}
+	switch err.Number {
+	case 2627, 2601:
+		e.Kind = ErrDuplicateKey
+	case 547:
+		// 外键与检查约束共用 547，按消息区分
+		if strings.Contains(err.Message, "FOREIGN KEY") || strings.Contains(err.Message, "REFERENCE") {
+			e.Kind = ErrForeignKey
+		} else {
+			e.Kind = ErrCheck
+		}
+	case 515:
+		e.Kind = ErrNotNull
+	case 1205:
+		e.Kind = ErrDeadlock
+	case 1222:
+		e.Kind = ErrTimeout
+	}
+}
+
+// sqlite 扩展错误码，消息如 UNIQUE constraint failed: item.name
+func classifySqlite(e *DBError, err *sqlite.Error) {
+	switch err.Code() {
+	case 2067, 1555:
+		e.Kind = ErrDuplicateKey
+	case 787:
+		e.Kind = ErrForeignKey
+	case 1299:
+		e.Kind = ErrNotNull
+	case 275:
+		e.Kind = ErrCheck
+	default:
+		if code := err.Code() & 0xff; code == 5 || code == 6 {
+			e.Kind = ErrTimeout
+		}
+		return
+	}
+	msg := err.Error()
+	i := strings.LastIndex(msg, "constraint failed: ")
+	if i < 0 {
+		return
+	}
+	detail, _, _ := strings.Cut(msg[i+len("constraint failed: "):], " (")
+	if e.Kind == ErrCheck {
+		e.Constraint = detail
+		return
+	}
+	// 复合唯一键为 item.a, item.b，取第一列
+	first, _, _ := strings.Cut(detail, ",")
+	if table, column, ok := strings.Cut(first, "."); ok {
+		e.Table, e.Column = table, column
+	}
+}
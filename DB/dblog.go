@@ -253,3 +253,13 @@ func (mapper *Mapper) debug(msg string) {
 		loggerPre["CUSTOM"].Debug(msg, "Sqlstr", mapper.Complete.Sql, "Args", mapper.Complete.Args)
 	}
 }
+
+// LogWarning 以 DB 日志记录警告，供 outbox 等扩展包使用，args 为键值对
+func LogWarning(title, msg string, args ...any) {
+	(&sqlLog{Message: msg, Title: title, Args: args}).logWARNING()
+}
+
+// LogError 以 DB 日志记录错误，供 outbox 等扩展包使用，args 为键值对
+func LogError(title, msg string, err error, args ...any) {
+	(&sqlLog{Message: msg, Title: title, Args: args}).logERROR(err)
+}
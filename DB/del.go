@@ -9,17 +9,23 @@ package DB
 
 import "database/sql"
 
-var Del = "DELETE FROM ${table} ${where} ${order} ${group}"
+var Del = "DELETE FROM ${table} ${where} ${group}${having} ${order}"
 
 // 删除数据
-func (mapper *Mapper) Del() (r sql.Result, err error) {
+func (mapper *Mapper) Del(conds ...Cond) (r sql.Result, err error) {
+	for _, cond := range conds {
+		mapper = mapper.whereCond(cond)
+	}
 	mapper.SqlTpl = Del
 	if mapper.Complete.Sql, err = mapper.getSql(); err != nil {
 		mapper.log("get sql error").logERROR(err)
 		return
 	}
 	mapper.debug("Del")
-	if r, err = mapper.Write().Exec(mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
+	if a, ok := mapper.auditor(); ok {
+		return mapper.auditExec(a, "delete")
+	}
+	if r, err = mapper.Write().ExecContext(mapper.context(), mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
 		return
 	}
 	return
@@ -28,14 +34,24 @@ func (mapper *Mapper) Del() (r sql.Result, err error) {
 /*
 删除数据并返回应向行数
 */
-func (mapper *Mapper) DelAffected() (affected int64, err error) {
+func (mapper *Mapper) DelAffected(conds ...Cond) (affected int64, err error) {
+	for _, cond := range conds {
+		mapper = mapper.whereCond(cond)
+	}
 	mapper.SqlTpl = Del
 	if mapper.Complete.Sql, err = mapper.getSql(); err != nil {
 		mapper.log("get sql error").logERROR(err)
 		return
 	}
 	mapper.debug("DelAffected")
-	if affected, err = mapper.Write().Affected(mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
+	if a, ok := mapper.auditor(); ok {
+		var r sql.Result
+		if r, err = mapper.auditExec(a, "delete"); err != nil {
+			return
+		}
+		return r.RowsAffected()
+	}
+	if affected, err = mapper.Write().AffectedContext(mapper.context(), mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
 		return
 	}
 	return
@@ -0,0 +1,199 @@
+package DB
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tjfoc/gmsm/sm4"
+)
+
+var (
+	ErrNoKeyring   = errors.New("encrypt field requires DB.SetKeyring")
+	ErrKeyNotFound = errors.New("encryption key not found")
+	ErrCiphertext  = errors.New("invalid ciphertext")
+	ErrNoBlindKey  = errors.New("blind index requires Keyring.BlindKey")
+)
+
+/*
+Keyring 字段加密密钥环，密文格式为 keyID$base64(nonce+密文)
+
+	新数据使用当前密钥加密，旧数据按密文中的 keyID 解密，便于轮换密钥
+*/
+type Keyring struct {
+	mu      sync.RWMutex
+	current string
+	keys    map[string]cipher.AEAD
+	blind   []byte
+}
+
+var keyring atomic.Pointer[Keyring]
+
+// SetKeyring 设置全局密钥环，encrypt 标签的字段读写时使用
+func SetKeyring(k *Keyring) {
+	keyring.Store(k)
+}
+
+func NewKeyring() *Keyring {
+	return &Keyring{keys: make(map[string]cipher.AEAD)}
+}
+
+/*
+Add 添加密钥，第一个添加的密钥为当前密钥
+
+	@id string；--密钥编号，不能包含 $
+	@aead cipher.AEAD；--自定义加密实现
+*/
+func (k *Keyring) Add(id string, aead cipher.AEAD) error {
+	if id == "" || strings.Contains(id, "$") {
+		return fmt.Errorf("invalid key id %q", id)
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[id] = aead
+	if k.current == "" {
+		k.current = id
+	}
+	return nil
+}
+
+// AddAES 添加 AES-GCM 密钥，key 长度为 16/24/32
+func (k *Keyring) AddAES(id string, key []byte) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	return k.addBlock(id, block)
+}
+
+// AddSM4 添加 SM4-GCM 密钥，key 长度为 16
+func (k *Keyring) AddSM4(id string, key []byte) error {
+	block, err := sm4.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	return k.addBlock(id, block)
+}
+
+func (k *Keyring) addBlock(id string, block cipher.Block) error {
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	return k.Add(id, aead)
+}
+
+// Use 设置加密使用的当前密钥
+func (k *Keyring) Use(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.keys[id]; !ok {
+		return ErrKeyNotFound
+	}
+	k.current = id
+	return nil
+}
+
+// BlindKey 设置盲索引的 HMAC 密钥，应与加密密钥不同且不随加密密钥轮换
+func (k *Keyring) BlindKey(key []byte) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.blind = key
+}
+
+// Encrypt 使用当前密钥加密
+func (k *Keyring) Encrypt(plain []byte) (string, error) {
+	k.mu.RLock()
+	id, aead := k.current, k.keys[k.current]
+	k.mu.RUnlock()
+	if aead == nil {
+		return "", ErrKeyNotFound
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return id + "$" + base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plain, []byte(id))), nil
+}
+
+// Decrypt 按密文中的 keyID 解密
+func (k *Keyring) Decrypt(text string) ([]byte, error) {
+	id, data, ok := strings.Cut(text, "$")
+	if !ok {
+		return nil, ErrCiphertext
+	}
+	k.mu.RLock()
+	aead := k.keys[id]
+	k.mu.RUnlock()
+	if aead == nil {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil || len(raw) < aead.NonceSize() {
+		return nil, ErrCiphertext
+	}
+	return aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], []byte(id))
+}
+
+// BlindIndex 计算盲索引，相同明文得到相同结果，用于等值查询；未设置 BlindKey 时返回 ErrNoBlindKey
+func (k *Keyring) BlindIndex(plain []byte) (string, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if len(k.blind) == 0 {
+		// 空密钥的 HMAC 可被离线穷举身份证号、手机号等低熵明文
+		return "", ErrNoBlindKey
+	}
+	mac := hmac.New(sha256.New, k.blind)
+	mac.Write(plain)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+/*
+BlindEq 按盲索引等值查询，未设置密钥环或 BlindKey 时查询返回错误
+
+	@column string；--盲索引列，即 encrypt 字段 blind: 选项指定的列
+	@val string；--明文
+*/
+func BlindEq(column string, val string) Cond {
+	k := keyring.Load()
+	if k == nil {
+		return badCond{err: ErrNoKeyring}
+	}
+	index, err := k.BlindIndex([]byte(val))
+	if err != nil {
+		return badCond{err: err}
+	}
+	return expr{sql: column + " = ?", args: []any{index}}
+}
+
+// 加密字段的写入值，withBlind 为 true 时同时计算盲索引
+func encryptValue(plain []byte, withBlind bool) (cipherText, blind string, err error) {
+	k := keyring.Load()
+	if k == nil {
+		return "", "", ErrNoKeyring
+	}
+	if cipherText, err = k.Encrypt(plain); err != nil || !withBlind {
+		return
+	}
+	if blind, err = k.BlindIndex(plain); err != nil {
+		return "", "", err
+	}
+	return
+}
+
+func decryptValue(text string) ([]byte, error) {
+	k := keyring.Load()
+	if k == nil {
+		return nil, ErrNoKeyring
+	}
+	return k.Decrypt(text)
+}
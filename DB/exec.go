@@ -1,27 +1,34 @@
 package DB
 
 import (
+	"context"
 	"database/sql"
 )
 
 func (db *ConnDB) Exec(sqlStr string, arg ...any) (r sql.Result, err error) {
+	return db.ExecContext(context.Background(), sqlStr, arg...)
+}
+
+// ExecContext 执行，ctx 传递给拦截器与驱动
+func (db *ConnDB) ExecContext(ctx context.Context, sqlStr string, arg ...any) (r sql.Result, err error) {
 	if db == nil {
 		return nil, ErrNoConn
 	}
 	args := handleNull(arg...)
 	query := Replace(sqlStr, "?", db.Sign)
 	db.log("Exec", query, args...).logDEBUG()
-	if r, err = db.DBFunc.Conn.Exec(query, args...); err == nil {
+	if r, err = db.exec(ctx, query, args...); err == nil {
 		return
 	}
 	db.log("exec error", query, args...).logERROR(err)
 	role := db.Conf.Role
-	if opErr := db.checkOpError(err); opErr {
-		if db = GetNewPool(role); db == nil {
-			return nil, ErrNoConn
-		}
+	if !db.checkOpError(err) {
+		return
+	}
+	if db = GetNewPool(role); db == nil {
+		return nil, ErrNoConn
 	}
-	if r, err = db.DBFunc.Conn.Exec(query, args...); err == nil {
+	if r, err = db.exec(ctx, query, args...); err == nil {
 		return
 	}
 	db.log("exec error", query, args...).logERROR(err)
@@ -29,24 +36,30 @@ func (db *ConnDB) Exec(sqlStr string, arg ...any) (r sql.Result, err error) {
 }
 
 func (db *ConnDB) Affected(sqlStr string, arg ...any) (Affected int64, err error) {
+	return db.AffectedContext(context.Background(), sqlStr, arg...)
+}
+
+// AffectedContext 执行并返回影响行数
+func (db *ConnDB) AffectedContext(ctx context.Context, sqlStr string, arg ...any) (Affected int64, err error) {
 	if db == nil {
 		return 0, ErrNoConn
 	}
-	args := handleNull(arg)
+	args := handleNull(arg...)
 	query := Replace(sqlStr, "?", db.Sign)
 	db.log("Affected", query, args...).logDEBUG()
 	var result sql.Result
-	if result, err = db.DBFunc.Conn.Exec(query, args...); err == nil {
+	if result, err = db.exec(ctx, query, args...); err == nil {
 		return result.RowsAffected()
 	}
 	db.log("Affected error", query, args...).logERROR(err)
 	role := db.Conf.Role
-	if opErr := db.checkOpError(err); opErr {
-		if db = GetNewPool(role); db == nil {
-			return 0, ErrNoConn
-		}
+	if !db.checkOpError(err) {
+		return
+	}
+	if db = GetNewPool(role); db == nil {
+		return 0, ErrNoConn
 	}
-	if result, err = db.DBFunc.Conn.Exec(query, args...); err == nil {
+	if result, err = db.exec(ctx, query, args...); err == nil {
 		return result.RowsAffected()
 	}
 	db.log("Affected error", query, args...).logERROR(err)
@@ -58,21 +71,23 @@ func MysqlAddReturnId(mapper *Mapper) (insertId int64, err error) {
 	if db == nil {
 		return 0, ErrNoConn
 	}
+	ctx := mapper.context()
 	args := handleNull(mapper.Complete.Args...)
 	var result sql.Result
 	query := Replace(mapper.Complete.Sql, "?", db.Sign)
 	db.log("MysqlAddReturnId", query, args...).logDEBUG()
-	if result, err = db.DBFunc.Conn.Exec(query, args...); err == nil {
+	if result, err = db.exec(ctx, query, args...); err == nil {
 		return result.LastInsertId()
 	}
 	db.log("MysqlAddReturnId error", query, args...).logERROR(err)
 	role := db.Conf.Role
-	if opErr := db.checkOpError(err); opErr {
-		if db = GetNewPool(role); db == nil {
-			return 0, ErrNoConn
-		}
+	if !db.checkOpError(err) {
+		return
 	}
-	if result, err = db.DBFunc.Conn.Exec(query, args...); err == nil {
+	if db = GetNewPool(role); db == nil {
+		return 0, ErrNoConn
+	}
+	if result, err = db.exec(ctx, query, args...); err == nil {
 		return result.LastInsertId()
 	}
 	db.log("MysqlAddReturnId error", query, args...).logERROR(err)
@@ -84,21 +99,23 @@ func PgsqlAddReturnId(mapper *Mapper) (insertId int64, err error) {
 	if db == nil {
 		return 0, ErrNoConn
 	}
+	ctx := mapper.context()
 	args := handleNull(mapper.Complete.Args...)
 	sqlStr := mapper.Complete.Sql + " RETURNING id"
 	query := Replace(sqlStr, "?", db.Sign)
 	db.log("PgsqlAddReturnId", query, args...).logDEBUG()
-	if err = db.DBFunc.Conn.QueryRow(query, args...).Scan(&insertId); err == nil {
+	if err = db.queryRowScan(ctx, query, args, &insertId); err == nil {
 		return
 	}
 	db.log("PgsqlAddReturnId", query, args...).logERROR(err)
 	role := db.Conf.Role
-	if opErr := db.checkOpError(err); opErr {
-		if db = GetNewPool(role); db == nil {
-			return 0, ErrNoConn
-		}
+	if !db.checkOpError(err) {
+		return
 	}
-	if err = db.DBFunc.Conn.QueryRow(query, args...).Scan(&insertId); err == nil {
+	if db = GetNewPool(role); db == nil {
+		return 0, ErrNoConn
+	}
+	if err = db.queryRowScan(ctx, query, args, &insertId); err == nil {
 		return
 	}
 	db.log("PgsqlAddReturnId", query, args...).logERROR(err)
@@ -110,21 +127,23 @@ func MssqlAddReturnId(mapper *Mapper) (insertId int64, err error) {
 	if db == nil {
 		return 0, ErrNoConn
 	}
+	ctx := mapper.context()
 	args := handleNull(mapper.Complete.Args...)
 	sqlStr := mapper.Complete.Sql + " ;SELECT SCOPE_IDENTITY();"
 	query := Replace(sqlStr, "?", db.Sign)
 	db.log("MssqlAddReturnId", query, args...).logDEBUG()
-	if err = db.DBFunc.Conn.QueryRow(query, args...).Scan(&insertId); err == nil {
+	if err = db.queryRowScan(ctx, query, args, &insertId); err == nil {
 		return
 	}
 	db.log("MssqlAddReturnId error", query, args...).logERROR(err)
 	role := db.Conf.Role
-	if opErr := db.checkOpError(err); opErr {
-		if db = GetNewPool(role); db == nil {
-			return 0, ErrNoConn
-		}
+	if !db.checkOpError(err) {
+		return
+	}
+	if db = GetNewPool(role); db == nil {
+		return 0, ErrNoConn
 	}
-	if err = db.DBFunc.Conn.QueryRow(query, args...).Scan(&insertId); err == nil {
+	if err = db.queryRowScan(ctx, query, args, &insertId); err == nil {
 		return
 	}
 	db.log("MssqlAddReturnId error", query, args...).logERROR(err)
@@ -135,7 +154,7 @@ func (mapper *Mapper) ExecSql(sql string, args ...any) (r sql.Result, err error)
 	mapper.Complete.Sql = sql
 	mapper.Complete.Args = args
 	mapper.debug("ExecSql")
-	if r, err = mapper.Write().Exec(mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
+	if r, err = mapper.Write().ExecContext(mapper.context(), mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
 		return
 	}
 	return
@@ -147,7 +166,7 @@ func (mapper *Mapper) Exec() (r sql.Result, err error) {
 		return
 	}
 	mapper.debug("Exec")
-	if r, err = mapper.Write().Exec(mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
+	if r, err = mapper.Write().ExecContext(mapper.context(), mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
 		return
 	}
 	return
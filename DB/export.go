@@ -0,0 +1,306 @@
+package DB
+
+import (
+	"archive/zip"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chris-liu-zh/qiao/tools"
+)
+
+type Exporter struct {
+	mapper *Mapper
+	model  reflect.Type
+	bom    bool
+	sheet  string
+}
+
+/*
+Export 导出查询结果，逐行写入，不整体加载到内存
+
+	@model struct指针；--列名取 json 标签，其次 db 标签；为 nil 时使用结果集列名
+*/
+func (mapper *Mapper) Export(model any) *Exporter {
+	e := &Exporter{mapper: mapper, sheet: "Sheet1"}
+	if model != nil {
+		e.model = reflect.TypeOf(model)
+		if e.model.Kind() == reflect.Pointer {
+			e.model = e.model.Elem()
+		}
+	}
+	return e
+}
+
+// BOM csv 写入 UTF-8 BOM，便于 Excel 识别中文
+func (e *Exporter) BOM(bom bool) *Exporter {
+	e.bom = bom
+	return e
+}
+
+// Sheet 设置 xlsx 工作表名称
+func (e *Exporter) Sheet(name string) *Exporter {
+	if name != "" {
+		e.sheet = name
+	}
+	return e
+}
+
+// CSV 导出为csv
+func (e *Exporter) CSV(w io.Writer) (err error) {
+	if e.bom {
+		if _, err = w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+			return
+		}
+	}
+	cw := csv.NewWriter(w)
+	record := []string{}
+	err = e.each(func(headers []string) error {
+		return cw.Write(headers)
+	}, func(values []any) error {
+		record = record[:0]
+		for _, v := range values {
+			record = append(record, exportString(v))
+		}
+		return cw.Write(record)
+	})
+	if err != nil {
+		return
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// JSONL 导出为 JSON Lines，每行一个对象
+func (e *Exporter) JSONL(w io.Writer) error {
+	var keys [][]byte
+	var line []byte
+	return e.each(func(headers []string) error {
+		for _, h := range headers {
+			key, err := json.Marshal(h)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, key)
+		}
+		return nil
+	}, func(values []any) error {
+		line = append(line[:0], '{')
+		for i, v := range values {
+			if i > 0 {
+				line = append(line, ',')
+			}
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			val, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			line = append(line, keys[i]...)
+			line = append(line, ':')
+			line = append(line, val...)
+		}
+		line = append(line, '}', '\n')
+		_, err := w.Write(line)
+		return err
+	})
+}
+
+// XLSX 导出为单工作表的 xlsx，单元格使用内联字符串
+func (e *Exporter) XLSX(w io.Writer) (err error) {
+	zw := zip.NewWriter(w)
+	defer tools.DeferErr(&err, zw.Close)
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, xmlEscape(e.sheet))},
+	}
+	for _, part := range parts {
+		var f io.Writer
+		if f, err = zw.Create(part.name); err != nil {
+			return
+		}
+		if _, err = io.WriteString(f, part.body); err != nil {
+			return
+		}
+	}
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return
+	}
+	if _, err = io.WriteString(sheet, xlsxSheetHead); err != nil {
+		return
+	}
+	var b strings.Builder
+	rowNum := 0
+	writeRow := func(values []any) error {
+		rowNum++
+		b.Reset()
+		fmt.Fprintf(&b, `<row r="%d">`, rowNum)
+		for _, v := range values {
+			if n, ok := exportNumber(v); ok {
+				fmt.Fprintf(&b, `<c><v>%s</v></c>`, n)
+				continue
+			}
+			fmt.Fprintf(&b, `<c t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, xmlEscape(exportString(v)))
+		}
+		b.WriteString(`</row>`)
+		_, err := io.WriteString(sheet, b.String())
+		return err
+	}
+	err = e.each(func(headers []string) error {
+		values := make([]any, len(headers))
+		for i, h := range headers {
+			values[i] = h
+		}
+		return writeRow(values)
+	}, writeRow)
+	if err != nil {
+		return
+	}
+	_, err = io.WriteString(sheet, xlsxSheetTail)
+	return
+}
+
+// 逐行读取结果集，先回调列名，再逐行回调值
+func (e *Exporter) each(header func([]string) error, row func([]any) error) (err error) {
+	mapper := e.mapper
+	if e.model != nil && e.model.Kind() != reflect.Struct {
+		return ErrNotStruct
+	}
+	rows, err := mapper.iterRows(e.model, "Export")
+	if err != nil {
+		return
+	}
+	defer tools.DeferErr(&err, rows.Close)
+	columns, err := rows.Columns()
+	if err != nil {
+		return
+	}
+
+	headers := columns
+	var fields, matched []readColumn
+	if e.model != nil {
+		if matched, err = matchColumns(e.model, columns); err != nil {
+			return
+		}
+		headers, fields = exportHeaders(e.model), readColumns(e.model, "", nil)
+	}
+	if err = header(headers); err != nil {
+		return
+	}
+	values := make([]any, len(headers))
+	pointer := make([]any, len(columns))
+	for i := range pointer {
+		pointer[i] = new(any)
+	}
+	for rows.Next() {
+		if e.model != nil {
+			item := reflect.New(e.model).Elem()
+			if err = rows.Scan(fieldPointers(item, matched)...); err != nil {
+				return
+			}
+			values = readValues(item, fields, values[:0])
+		} else {
+			if err = rows.Scan(pointer...); err != nil {
+				return
+			}
+			for i := range pointer {
+				values[i] = *pointer[i].(*any)
+			}
+		}
+		if err = row(values); err != nil {
+			return
+		}
+	}
+	return rows.Err()
+}
+
+// 导出列名：json 标签优先，其次 db 标签，最后字段名转下划线
+func exportHeaders(t reflect.Type) (headers []string) {
+	for _, c := range readColumns(t, "", nil) {
+		name, _, _ := strings.Cut(c.field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			name = c.column
+		}
+		headers = append(headers, name)
+	}
+	return
+}
+
+// 获取结构体可读字段的值，指针取其指向的值，driver.Valuer 取其驱动值
+func readValues(item reflect.Value, fields []readColumn, values []any) []any {
+	for _, c := range fields {
+		field := item.FieldByIndex(c.index)
+		if field.Kind() == reflect.Pointer {
+			if field.IsNil() {
+				values = append(values, nil)
+				continue
+			}
+			field = field.Elem()
+		}
+		if v, ok := field.Interface().(driver.Valuer); ok {
+			val, _ := v.Value()
+			values = append(values, val)
+			continue
+		}
+		values = append(values, field.Interface())
+	}
+	return values
+}
+
+func exportString(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	case time.Time:
+		return val.Format(time.DateTime)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+func exportNumber(v any) (string, bool) {
+	switch val := v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(val), true
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', -1, 32), true
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxSheetHead = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetTail = `</sheetData></worksheet>`
+)
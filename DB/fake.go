@@ -0,0 +1,371 @@
+package DB
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 测试模式使用的驱动名
+const fakeDriver = "qiao-fake"
+
+var (
+	fakeRecorders sync.Map
+	fakeSeq       atomic.Int64
+	fakeOnce      sync.Once
+)
+
+// RegisterFake 注册测试模式驱动，NewFake 会自动调用，不使用测试模式时不注册
+func RegisterFake() {
+	fakeOnce.Do(func() { sql.Register(fakeDriver, fakeDrv{}) })
+}
+
+// Call 驱动收到的一次调用，Sql 为替换占位符后的语句，命名参数记录为 sql.NamedArg
+type Call struct {
+	Sql  string
+	Args []any
+}
+
+// Recorder 测试模式下记录驱动收到的 sql 及参数，并按脚本返回结果
+type Recorder struct {
+	mu      sync.Mutex
+	calls   []Call
+	scripts []*Script
+	down    bool
+}
+
+// Script 预设的返回结果，按顺序匹配，每条只使用一次
+type Script struct {
+	contains string
+	sets     []fakeSet
+	result   driver.Result
+	err      error
+}
+
+type fakeSet struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+/*
+NewFake 以测试模式加入连接池，不连接真实数据库
+
+	@conf Config；--按 Type 生成对应方言的 sql，Role 决定加入的池
+*/
+func NewFake(conf Config) (*Recorder, error) {
+	RegisterFake()
+	r := &Recorder{}
+	conf.Open = true
+	conf.Driver = fakeDriver
+	conf.Dsn = fmt.Sprintf("fake-%d", fakeSeq.Add(1))
+	fakeRecorders.Store(conf.Dsn, r)
+	if err := conf.NewDB(); err != nil {
+		fakeRecorders.Delete(conf.Dsn)
+		return nil, err
+	}
+	return r, nil
+}
+
+// Calls 已记录的调用
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// Last 最后一次调用
+func (r *Recorder) Last() Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.calls) == 0 {
+		return Call{}
+	}
+	return r.calls[len(r.calls)-1]
+}
+
+// Reset 清空记录及未使用的脚本
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+	r.scripts = nil
+}
+
+// Down 模拟数据库宕机，ping 返回网络错误
+func (r *Recorder) Down(down bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.down = down
+}
+
+/*
+Expect 添加脚本
+
+	@contains string；--sql 包含该字符串时匹配，不区分大小写，为空时匹配任意语句
+*/
+func (r *Recorder) Expect(contains string) *Script {
+	s := &Script{contains: contains}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scripts = append(r.scripts, s)
+	return s
+}
+
+// Rows 返回结果集，多次调用时返回多个结果集
+func (s *Script) Rows(columns []string, rows ...[]any) *Script {
+	set := fakeSet{columns: columns}
+	for _, row := range rows {
+		values := make([]driver.Value, len(row))
+		for i, v := range row {
+			if dv, err := driver.DefaultParameterConverter.ConvertValue(v); err == nil {
+				v = dv
+			}
+			values[i] = v
+		}
+		set.rows = append(set.rows, values)
+	}
+	s.sets = append(s.sets, set)
+	return s
+}
+
+// Result 返回执行结果
+func (s *Script) Result(lastInsertId, rowsAffected int64) *Script {
+	s.result = fakeResult{lastInsertId, rowsAffected}
+	return s
+}
+
+// Error 返回错误
+func (s *Script) Error(err error) *Script {
+	s.err = err
+	return s
+}
+
+// OpError 返回网络错误，用于测试断线重连及主从切换
+func (s *Script) OpError() *Script {
+	return s.Error(&net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")})
+}
+
+// 记录调用并取出第一条匹配的脚本
+func (r *Recorder) call(query string, args []driver.NamedValue) *Script {
+	values := make([]any, len(args))
+	for i, a := range args {
+		values[i] = a.Value
+		if a.Name != "" {
+			values[i] = sql.Named(a.Name, a.Value)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, Call{Sql: query, Args: values})
+	for i, s := range r.scripts {
+		if strings.Contains(strings.ToLower(query), strings.ToLower(s.contains)) {
+			r.scripts = append(r.scripts[:i], r.scripts[i+1:]...)
+			return s
+		}
+	}
+	return &Script{}
+}
+
+type fakeDrv struct{}
+
+func (fakeDrv) Open(name string) (driver.Conn, error) {
+	r, ok := fakeRecorders.Load(name)
+	if !ok {
+		return nil, fmt.Errorf("fake recorder %s not found", name)
+	}
+	return &fakeConn{rec: r.(*Recorder)}, nil
+}
+
+type fakeConn struct {
+	rec *Recorder
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(_ context.Context, _ driver.TxOptions) (driver.Tx, error) {
+	if s := c.rec.call("BEGIN", nil); s.err != nil {
+		return nil, s.err
+	}
+	return fakeTx{conn: c}, nil
+}
+
+func (c *fakeConn) Ping(context.Context) error {
+	c.rec.mu.Lock()
+	defer c.rec.mu.Unlock()
+	if c.rec.down {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	}
+	return nil
+}
+
+// 参数原样记录，不做类型转换
+func (c *fakeConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	var readErr error
+	// LOAD DATA LOCAL INFILE 'Reader::name' 读取导入的数据，记录为唯一参数
+	if r, ok := fakeLoadData(query); ok {
+		var data []byte
+		data, readErr = io.ReadAll(r)
+		args = []driver.NamedValue{{Ordinal: 1, Value: string(data)}}
+	}
+	s := c.rec.call(query, args)
+	if readErr != nil {
+		return nil, readErr
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.result == nil {
+		return fakeResult{}, nil
+	}
+	return s.result, nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	s := c.rec.call(query, args)
+	if s.err != nil {
+		return nil, s.err
+	}
+	sets := s.sets
+	if len(sets) == 0 {
+		sets = []fakeSet{{}}
+	}
+	return &fakeRows{sets: sets}, nil
+}
+
+// BulkLoad 登记的 LOAD DATA 管道
+func fakeLoadData(query string) (io.Reader, bool) {
+	_, name, ok := strings.Cut(query, "'Reader::")
+	if !ok {
+		return nil, false
+	}
+	name, _, _ = strings.Cut(name, "'")
+	r, ok := loadDataReaders.Load(name)
+	if !ok {
+		return nil, false
+	}
+	return r.(io.Reader), true
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *fakeStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *fakeStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func (s *fakeStmt) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+type fakeTx struct {
+	conn *fakeConn
+}
+
+func (tx fakeTx) Commit() error {
+	return tx.conn.rec.call("COMMIT", nil).err
+}
+
+func (tx fakeTx) Rollback() error {
+	return tx.conn.rec.call("ROLLBACK", nil).err
+}
+
+type fakeResult struct {
+	lastInsertId int64
+	rowsAffected int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) {
+	return r.lastInsertId, nil
+}
+
+func (r fakeResult) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+type fakeRows struct {
+	sets []fakeSet
+	set  int
+	row  int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.sets[r.set].columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	set := r.sets[r.set]
+	if r.row >= len(set.rows) {
+		return io.EOF
+	}
+	copy(dest, set.rows[r.row])
+	r.row++
+	return nil
+}
+
+func (r *fakeRows) HasNextResultSet() bool {
+	return r.set < len(r.sets)-1
+}
+
+func (r *fakeRows) NextResultSet() error {
+	if !r.HasNextResultSet() {
+		return io.EOF
+	}
+	r.set++
+	r.row = 0
+	return nil
+}
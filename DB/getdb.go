@@ -10,15 +10,15 @@ func GetRand() *rand.Rand {
 }
 
 func GetSlave() *ConnDB {
-	return Pool.Slave.getDB()
+	return rolePool("slave").getDB()
 }
 
 func GetMaster() *ConnDB {
-	return Pool.Master.getDB()
+	return rolePool("master").getDB()
 }
 
 func GetAlone() *ConnDB {
-	return Pool.Alone.getDB()
+	return rolePool("alone").getDB()
 }
 
 func (mapper *Mapper) Read() *ConnDB {
@@ -44,7 +44,7 @@ func GetNewPool(Role string) (conn *ConnDB) {
 		if conn = GetMasterDB(); conn != nil {
 			return
 		}
-		if Pool.SwitchRole {
+		if switchRole, _, _ := poolOptions(); switchRole {
 			if conn = GetSlaveDB(); conn != nil {
 				return
 			}
@@ -65,19 +65,22 @@ func GetNewPool(Role string) (conn *ConnDB) {
 }
 
 func GetMasterDB() *ConnDB {
-	return Pool.Master.getOnlineDB()
+	return rolePool("master").getOnlineDB()
 }
 
 func GetSlaveDB() *ConnDB {
-	return Pool.Slave.getOnlineDB()
+	return rolePool("slave").getOnlineDB()
 }
 
 func (rolePool *PoolConn) getOnlineDB() *ConnDB {
-	if Pool.Master.PoolNum == 0 {
+	poolMu.RLock()
+	masterNum := Pool.Master.PoolNum
+	poolMu.RUnlock()
+	if masterNum == 0 {
 		return nil
 	}
 	for i := range rolePool.DBConn {
-		conn := &rolePool.DBConn[i]
+		conn := rolePool.DBConn[i]
 		if conn.IsClose {
 			continue
 		}
@@ -97,7 +100,7 @@ func (rolePool *PoolConn) getDB() *ConnDB {
 	// 收集可用连接的索引
 	avail := make([]int, 0, n)
 	for i := range n {
-		conn := &rolePool.DBConn[i]
+		conn := rolePool.DBConn[i]
 		if conn.IsClose {
 			continue
 		}
@@ -110,5 +113,5 @@ func (rolePool *PoolConn) getDB() *ConnDB {
 
 	// 随机选择一个可用连接
 	idx := GetRand().Intn(len(avail))
-	return &rolePool.DBConn[avail[idx]]
+	return rolePool.DBConn[avail[idx]]
 }
@@ -41,7 +41,7 @@ func (mapper *Mapper) getInsert(data any) *Mapper {
 			mapper.Debris.table = tools.CamelCaseToUdnderscore(elem.Type().Name())
 		}
 		for i := range elem.NumField() {
-			if !elem.Field(i).IsValid() || (elem.Field(i).Kind() == reflect.Pointer && elem.Field(i).IsNil()) || !elem.Field(i).CanInterface() {
+			if !elem.Field(i).IsValid() || (elem.Field(i).Kind() == reflect.Pointer && elem.Field(i).IsNil()) || !elem.Field(i).CanInterface() || isRelation(elem.Type().Field(i)) {
 				continue
 			}
 			fields := strings.Split(elem.Type().Field(i).Tag.Get("db"), ";")
@@ -51,9 +51,18 @@ func (mapper *Mapper) getInsert(data any) *Mapper {
 				} else {
 					field.WriteString(tools.CamelCaseToUdnderscore(elem.Type().Field(i).Name) + `,`)
 				}
-
-				mapper.Complete.Args = append(mapper.Complete.Args, elem.Field(i).Interface())
+				val, blind, index, err := writeValue(fields, elem.Field(i))
+				if err != nil {
+					mapper.err = err
+					return mapper
+				}
+				mapper.Complete.Args = append(mapper.Complete.Args, val)
 				l++
+				if blind != "" {
+					field.WriteString(blind + `,`)
+					mapper.Complete.Args = append(mapper.Complete.Args, index)
+					l++
+				}
 			}
 		}
 	}
@@ -62,11 +71,17 @@ func (mapper *Mapper) getInsert(data any) *Mapper {
 		for k, v := range data.(map[string]any) {
 			field.WriteString(tools.CamelCaseToUdnderscore(k) + `,`)
 			mapper.Complete.Args = append(mapper.Complete.Args, v)
+			l++
 		}
 	}
 
-	mapper.Debris.sign = Placeholders(l)
-	mapper.Debris.field = strings.TrimRight(field.String(), ",")
+	columns, err := mapper.tenantInsert(strings.Split(strings.TrimRight(field.String(), ","), ",")[:l])
+	if err != nil {
+		mapper.err = err
+		return mapper
+	}
+	mapper.Debris.sign = Placeholders(len(columns))
+	mapper.Debris.field = strings.Join(columns, ",")
 	mapper.SqlTpl = Insert
 	return mapper
 }
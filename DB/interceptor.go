@@ -0,0 +1,93 @@
+package DB
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+/*
+Operation 拦截器中的一次语句执行
+
+	Sql 为已替换占位符的方言 sql，拦截器在调用 next 前修改 Sql / Args 即可改写语句；
+	next 返回后 Rows / Result / Err / Elapsed 为执行结果，Err 已按 DBError 归类
+*/
+type Operation struct {
+	Kind    string // query / queryRow / exec
+	Sql     string
+	Args    []any
+	Dialect string     // 数据库类型
+	Role    string     // 连接角色 master / slave / alone
+	Title   string     // 连接标题
+	Tx      bool       // 是否在事务中
+	Rows    *sql.Rows  // query 的结果
+	Result  sql.Result // exec 的结果
+	Err     error
+	Elapsed time.Duration
+}
+
+type Handler func(ctx context.Context, op *Operation) error
+
+/*
+Interceptor 语句拦截器，须调用 next 执行语句，不调用则语句不会执行
+
+	DB.Use(func(ctx context.Context, op *DB.Operation, next DB.Handler) error {
+		if strings.HasPrefix(op.Sql, "delete") && !strings.Contains(op.Sql, "where") {
+			return errors.New("delete without where")
+		}
+		return next(ctx, op)
+	})
+*/
+type Interceptor func(ctx context.Context, op *Operation, next Handler) error
+
+var (
+	interceptorMu sync.RWMutex
+	interceptors  []Interceptor
+)
+
+/*
+Use 添加拦截器，按添加顺序由外到内包裹每条语句
+
+	对 ConnDB 与 Begin 事务中的语句生效，包括 Audit、BulkLoad、Call、fixtures、outbox 内部执行的语句；
+	直接使用 Begin.Tx 或 ConnDB.DBFunc.Conn 执行的语句不经过拦截器，BulkLoad 的 CopyIn 只拦截最后的提交
+*/
+func Use(interceptor ...Interceptor) {
+	interceptorMu.Lock()
+	defer interceptorMu.Unlock()
+	interceptors = append(append([]Interceptor(nil), interceptors...), interceptor...)
+}
+
+// ClearInterceptors 移除全部拦截器
+func ClearInterceptors() {
+	interceptorMu.Lock()
+	defer interceptorMu.Unlock()
+	interceptors = nil
+}
+
+// 按拦截器链执行语句，final 为实际执行
+func intercept(ctx context.Context, op *Operation, final Handler) error {
+	interceptorMu.RLock()
+	chain := interceptors
+	interceptorMu.RUnlock()
+	h := func(ctx context.Context, op *Operation) error {
+		start := time.Now()
+		op.Err = classifyError(op.Dialect, final(ctx, op))
+		op.Elapsed = time.Since(start)
+		return op.Err
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		in, next := chain[i], h
+		h = func(ctx context.Context, op *Operation) error { return in(ctx, op, next) }
+	}
+	op.Err = h(ctx, op)
+	if op.Err != nil && op.Rows != nil {
+		op.Rows.Close()
+		op.Rows = nil
+	}
+	return op.Err
+}
+
+func (db *ConnDB) operation(kind, query string, args []any) *Operation {
+	return &Operation{Kind: kind, Sql: query, Args: args, Dialect: db.Conf.Type, Role: db.Conf.Role, Title: db.Conf.Title}
+}
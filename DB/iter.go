@@ -0,0 +1,124 @@
+package DB
+
+import (
+	"database/sql"
+	"iter"
+	"reflect"
+
+	"github.com/chris-liu-zh/qiao/tools"
+)
+
+/*
+Iter 逐行读取结构体数据，循环提前结束时自动关闭结果集
+
+	for item, err := range DB.Iter[User](DB.QiaoDB().Find("age > ?", 18)) {}
+
+支持 Limit、Join，以及先调用 Query 得到的结果集；每次遍历重新查询，可重复遍历
+*/
+func Iter[T any](mapper *Mapper) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		elem := reflect.New(reflect.TypeFor[T]()).Elem()
+		if elem.Kind() != reflect.Struct {
+			yield(zero, ErrNotStruct)
+			return
+		}
+		rows, err := mapper.iterRows(elem.Type(), "Iter")
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer rows.Close()
+		columns, err := rows.Columns()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		fields, err := matchColumns(elem.Type(), columns)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		for rows.Next() {
+			var item T
+			if err := rows.Scan(fieldPointers(reflect.ValueOf(&item).Elem(), fields)...); err != nil {
+				mapper.log("iter scan error").logERROR(err)
+				yield(zero, err)
+				return
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// IterMap 逐行读取map数据，循环提前结束时自动关闭结果集
+func (mapper *Mapper) IterMap() iter.Seq2[map[string]any, error] {
+	return func(yield func(map[string]any, error) bool) {
+		rows, err := mapper.iterRows(nil, "IterMap")
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer rows.Close()
+		columns, err := rows.Columns()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		length := len(columns)
+		pointer := make([]any, length)
+		for i := range length {
+			pointer[i] = new(any)
+		}
+		for rows.Next() {
+			if err = rows.Scan(pointer...); err != nil {
+				mapper.log("iter scan error").logERROR(err)
+				yield(nil, err)
+				return
+			}
+			row := make(map[string]any, length)
+			for i := range length {
+				row[columns[i]] = *pointer[i].(*any)
+			}
+			if !yield(row, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// 取走 Query 留下的结果集，没有时重新查询；结果集由调用方关闭，Mapper 不再持有，可多次遍历
+func (mapper *Mapper) iterRows(model reflect.Type, msg string) (rows *sql.Rows, err error) {
+	if rows = mapper.sqlRows; rows != nil {
+		mapper.sqlRows = nil
+		return
+	}
+	if !mapper.raw {
+		if model != nil {
+			if _, err = mapper.getMapper(reflect.New(model).Elem()); err != nil {
+				return
+			}
+		} else if mapper.Complete.Sql, err = mapper.getSql(); err != nil {
+			mapper.log("get sql error").logERROR(err)
+			return
+		}
+	}
+	mapper.debug(msg)
+	return mapper.Read().QueryContext(mapper.context(), mapper.Complete.Sql, mapper.Complete.Args...)
+}
+
+// 关闭并释放结果集，之后的 Iter 等重新查询
+func (mapper *Mapper) closeRows(err *error) {
+	if rows := mapper.sqlRows; rows != nil {
+		mapper.sqlRows = nil
+		tools.DeferErr(err, rows.Close)
+	}
+}
@@ -31,11 +31,43 @@ import (
 
 // join
 
-const SelectJoin = "select ${field} from (select ${join_field} from ${table} ${join} ${where} ${order} ${group}) temp"
+const SelectJoin = "select ${field} from (select ${join_field} from ${table}${join} ${where} ${group}${having} ${order}) temp"
 
 func (mapper *Mapper) Join(join, joinField, on string) *Mapper {
 	mapper.Debris.joinField = joinField
-	mapper.Debris.join = fmt.Sprintf("%s on %s", join, on)
+	mapper.Debris.join = fmt.Sprintf(" %s on %s", join, on)
 	mapper.SqlTpl = SelectJoin
 	return mapper
 }
+
+/*
+LeftJoin 左连接，可链式多次调用
+
+	@table string；--连接表
+	@alias string；--表别名，字段使用 alias.column 引用
+	@on string；--连接条件
+*/
+func (mapper *Mapper) LeftJoin(table, alias, on string) *Mapper {
+	return mapper.addJoin("left join", table, alias, on)
+}
+
+// InnerJoin 内连接，可链式多次调用
+func (mapper *Mapper) InnerJoin(table, alias, on string) *Mapper {
+	return mapper.addJoin("inner join", table, alias, on)
+}
+
+// RightJoin 右连接，可链式多次调用
+func (mapper *Mapper) RightJoin(table, alias, on string) *Mapper {
+	return mapper.addJoin("right join", table, alias, on)
+}
+
+// Alias 设置主表别名，用于连接查询
+func (mapper *Mapper) Alias(alias string) *Mapper {
+	mapper.Debris.alias = alias
+	return mapper
+}
+
+func (mapper *Mapper) addJoin(kind, table, alias, on string) *Mapper {
+	mapper.Debris.join += fmt.Sprintf(" %s %s %s on %s", kind, table, alias, on)
+	return mapper
+}
@@ -0,0 +1,94 @@
+package DB
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Mapper 所用连接的数据库类型，用于生成方言相关的 sql
+func (mapper *Mapper) dialect() string {
+	db := mapper.Read()
+	if db == nil {
+		return ""
+	}
+	return db.Conf.Type
+}
+
+/*
+JSONPath JSON 字段取值表达式，结果为文本，可用作 Eq / Gt / In 等条件的列；按 Mapper 所用连接的数据库类型生成
+
+	@column string；--json 字段
+	@path string；--路径，如 $.address.city 或 $[0]，单引号会被去除
+
+	pgsql: column->'address'->>'city'
+	mysql: column->>'$.address.city'
+	mssql: JSON_VALUE(column, '$.address.city')
+	sqlite: json_extract(column, '$.address.city')
+
+	mapper := DB.QiaoDB().Table("profile")
+	mapper.Find(mapper.JSONEq("address", "$.city", "Hangzhou"))
+*/
+func (mapper *Mapper) JSONPath(column, path string) string {
+	path = strings.ReplaceAll(path, "'", "")
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	full := "$"
+	if path != "" && path[0] != '[' {
+		full += "."
+	}
+	full += path
+	switch mapper.dialect() {
+	case "pgsql":
+		var b strings.Builder
+		b.WriteString(column)
+		keys := jsonKeys(path)
+		for i, key := range keys {
+			if i == len(keys)-1 {
+				b.WriteString("->>")
+			} else {
+				b.WriteString("->")
+			}
+			if _, err := strconv.Atoi(key); err == nil {
+				b.WriteString(key)
+			} else {
+				b.WriteString("'" + key + "'")
+			}
+		}
+		return b.String()
+	case "mysql":
+		return fmt.Sprintf("%s->>'%s'", column, full)
+	case "mssql":
+		return fmt.Sprintf("JSON_VALUE(%s, '%s')", column, full)
+	default:
+		return fmt.Sprintf("json_extract(%s, '%s')", column, full)
+	}
+}
+
+// 拆分路径 a.b[0].c 为 a b 0 c
+func jsonKeys(path string) (keys []string) {
+	for _, part := range strings.Split(path, ".") {
+		for part != "" {
+			i := strings.IndexByte(part, '[')
+			if i < 0 {
+				keys = append(keys, part)
+				break
+			}
+			if i > 0 {
+				keys = append(keys, part[:i])
+			}
+			j := strings.IndexByte(part, ']')
+			if j < i {
+				keys = append(keys, part[i+1:])
+				break
+			}
+			keys = append(keys, part[i+1:j])
+			part = part[j+1:]
+		}
+	}
+	return
+}
+
+// JSONEq JSON 字段路径值等于 val
+func (mapper *Mapper) JSONEq(column, path string, val any) Cond {
+	return compare(mapper.JSONPath(column, path), "=", val)
+}
@@ -1,6 +1,7 @@
 package DB
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"reflect"
@@ -17,11 +18,20 @@ const (
 )
 
 type Mapper struct {
-	SqlTpl   string
-	Role     string
-	sqlRows  *sql.Rows
-	Debris   SqlDebris
-	Complete SqlComplete
+	SqlTpl    string
+	Role      string
+	sqlRows   *sql.Rows
+	Debris    SqlDebris
+	Complete  SqlComplete
+	compose   compose
+	ctx       context.Context
+	err       error
+	statement string   //命名语句渲染后的 sql
+	preload   []string //Preload 的关联字段
+	raw       bool     //Query / QueryRow 的 sql，重新查询时原样执行
+
+	withoutTenant bool //不做租户隔离
+	tenantScoped  bool //已加入租户条件
 }
 
 type SqlComplete struct {
@@ -37,9 +47,11 @@ type SqlDebris struct {
 	where     string `tpl:"where"`
 	set       string `tpl:"set"`
 	group     string `tpl:"group"`
+	having    string `tpl:"having"`
 	order     string `tpl:"order"`
 	sign      string `tpl:"sign"`
 	join      string `tpl:"join"`
+	alias     string `tpl:"alias"`
 }
 
 type options func(*Mapper)
@@ -67,19 +79,19 @@ func QiaoDB(opt ...options) *Mapper {
 /*
 Set 设置
 
-	@set string map struct；--设置updata set参数
+	@set string map struct；--设置updata set参数，参数始终排在 where 参数之前
 */
 func (mapper *Mapper) Set(set any, args ...any) *Mapper {
 	v := reflect.ValueOf(set)
 	if v.Kind() == reflect.String {
 		mapper.Debris.set += set.(string)
-		mapper.Complete.Args = append(mapper.Complete.Args, args...)
+		mapper.compose.setArgs = append(mapper.compose.setArgs, args...)
 		return mapper
 	}
 	if v.Kind() == reflect.Map {
 		for k, v := range set.(map[string]any) {
 			mapper.Debris.set += fmt.Sprintf(`%s = ?,`, tools.CamelCaseToUdnderscore(k))
-			mapper.Complete.Args = append(mapper.Complete.Args, v)
+			mapper.compose.setArgs = append(mapper.compose.setArgs, v)
 		}
 		mapper.Debris.set = strings.TrimRight(mapper.Debris.set, ",")
 		return mapper
@@ -94,17 +106,27 @@ func (mapper *Mapper) Set(set any, args ...any) *Mapper {
 		var column string
 		if elem.Kind() == reflect.Struct {
 			for i := range l {
-				if !elem.Field(i).IsValid() || (elem.Field(i).Kind() == reflect.Pointer && elem.Field(i).IsNil()) || !elem.Field(i).CanInterface() {
+				if !elem.Field(i).IsValid() || (elem.Field(i).Kind() == reflect.Pointer && elem.Field(i).IsNil()) || !elem.Field(i).CanInterface() || isRelation(elem.Type().Field(i)) {
 					continue
 				}
 				fields := strings.Split(elem.Type().Field(i).Tag.Get("db"), ";")
-				if WritableField(fields) {
+				// 租户列不随更新改变
+				if WritableField(fields) && !tagOption(fields, "tenant") {
 					if c := getColumn(fields); c != "" {
 						column += c + "=?,"
 					} else {
 						column += tools.CamelCaseToUdnderscore(elem.Type().Field(i).Name) + `=?,`
 					}
-					mapper.Complete.Args = append(mapper.Complete.Args, elem.Field(i).Interface())
+					val, blind, index, err := writeValue(fields, elem.Field(i))
+					if err != nil {
+						mapper.err = err
+						return mapper
+					}
+					mapper.compose.setArgs = append(mapper.compose.setArgs, val)
+					if blind != "" {
+						column += blind + "=?,"
+						mapper.compose.setArgs = append(mapper.compose.setArgs, index)
+					}
 				}
 			}
 		}
@@ -121,6 +143,37 @@ func (mapper *Mapper) GroupBy(group string) *Mapper {
 	return mapper
 }
 
+/*
+Having 分组过滤，可多次调用，以 and 连接
+
+	@having string 或 Cond；--过滤条件
+	@args []any；--条件值
+*/
+func (mapper *Mapper) Having(having any, args ...any) *Mapper {
+	var s string
+	switch h := having.(type) {
+	case string:
+		s = h
+	case Cond:
+		if err := condError(h); err != nil {
+			mapper.err = err
+			return mapper
+		}
+		s, args = h.Build()
+	}
+	if s == "" {
+		return mapper
+	}
+	if mapper.Debris.having == "" {
+		mapper.Debris.having = " having "
+	} else {
+		mapper.Debris.having += " and "
+	}
+	mapper.Debris.having += fmt.Sprintf("(%s)", s)
+	mapper.compose.havingArgs = append(mapper.compose.havingArgs, args...)
+	return mapper
+}
+
 // Table 设置表
 func (mapper *Mapper) Table(tableName string) *Mapper {
 	mapper.Debris.table = tableName
@@ -1,6 +1,7 @@
 package DB
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -27,7 +28,7 @@ type DBPool struct {
 
 type PoolConn struct {
 	PoolNum int `json:"PoolNum"`
-	DBConn  []ConnDB
+	DBConn  []*ConnDB
 }
 
 type Config struct {
@@ -46,6 +47,9 @@ type Config struct {
 	MaxOpen     int    `json:"MaxOpen"`
 	TimeOut     int    `json:"TimeOut"`
 	MaxIdleTime string `json:"MaxIdleTime"`
+	StmtCache   int    `json:"StmtCache"` //预编译语句缓存数量,0为不缓存
+	Driver      string `json:"Driver"`    //自定义驱动名,为空时按 Type 选择
+	SSLMode     string `json:"SSLMode"`   //pgsql 的 sslmode,为空时为 disable
 }
 
 type ConnDB struct {
@@ -56,6 +60,10 @@ type ConnDB struct {
 	IsClose  bool   `json:"IsClose"`  //连接是否关闭
 	RetryIng bool   `json:"RetryIng"` //是否正在重连
 	DBFunc   dbFunc
+	stmts    *stmtCache
+	source   Config //加入连接池时的原始配置,重新加载时按此对比
+	closed   context.Context
+	shutdown context.CancelFunc //关闭连接时通知重连退出
 }
 
 type dbFunc struct {
@@ -86,6 +94,8 @@ func init() {
 }
 
 func PrintPool() {
+	poolMu.RLock()
+	defer poolMu.RUnlock()
 	fmt.Println("数据库连接池信息")
 	fmt.Printf("总连接数: %d\n", Pool.PoolCount)
 	fmt.Printf("主库连接数: %d\n", Pool.Master.PoolNum)
@@ -103,14 +113,10 @@ func PrintPool() {
 }
 
 func Reconnect(role string, id int) {
-	var dbs []ConnDB
+	var dbs []*ConnDB
 	switch role {
-	case "master":
-		dbs = Pool.Master.DBConn
-	case "slave":
-		dbs = Pool.Slave.DBConn
-	case "alone":
-		dbs = Pool.Alone.DBConn
+	case "master", "slave", "alone":
+		dbs = rolePool(role).DBConn
 	default:
 		fmt.Println("role只支持master,slave,alone")
 	}
@@ -130,14 +136,14 @@ func Reconnect(role string, id int) {
 func PGpage(mapper *Mapper, sizePage ...int) *Mapper {
 	sp := len(sizePage)
 	if sp == 1 {
-		mapper.SqlTpl = `select ${field} from ${table} ${where} ${order} ${group} LIMIT ?`
-		mapper.Complete.Args = append(mapper.Complete.Args, sizePage[0])
+		mapper.SqlTpl = `select ${field} from ${table}${join} ${where} ${group}${having} ${order} LIMIT ?`
+		mapper.compose.limitArgs = []any{sizePage[0]}
 	}
 	if sp > 1 {
 		size := sizePage[0]
 		page := sizePage[1]
-		mapper.SqlTpl = `select ${field} from ${table} ${where} ${order} ${group} LIMIT ? OFFSET ?`
-		mapper.Complete.Args = append(mapper.Complete.Args, size, (page-1)*size)
+		mapper.SqlTpl = `select ${field} from ${table}${join} ${where} ${group}${having} ${order} LIMIT ? OFFSET ?`
+		mapper.compose.limitArgs = []any{size, (page - 1) * size}
 	}
 	return mapper
 }
@@ -145,14 +151,14 @@ func PGpage(mapper *Mapper, sizePage ...int) *Mapper {
 func MYpage(mapper *Mapper, sizePage ...int) *Mapper {
 	sp := len(sizePage)
 	if sp == 1 {
-		mapper.SqlTpl = `select ${field} from ${table} ${where} ${order} ${group} LIMIT ?`
-		mapper.Complete.Args = append(mapper.Complete.Args, sizePage[0])
+		mapper.SqlTpl = `select ${field} from ${table}${join} ${where} ${group}${having} ${order} LIMIT ?`
+		mapper.compose.limitArgs = []any{sizePage[0]}
 	}
 	if sp > 1 {
 		size := sizePage[0]
 		page := sizePage[1]
-		mapper.SqlTpl = `select ${field} from ${table} ${where} ${order} ${group} LIMIT ?,?`
-		mapper.Complete.Args = append(mapper.Complete.Args, (page-1)*size, size)
+		mapper.SqlTpl = `select ${field} from ${table}${join} ${where} ${group}${having} ${order} LIMIT ?,?`
+		mapper.compose.limitArgs = []any{(page - 1) * size, size}
 	}
 	return mapper
 }
@@ -160,14 +166,15 @@ func MYpage(mapper *Mapper, sizePage ...int) *Mapper {
 func MSpage(mapper *Mapper, sizePage ...int) *Mapper {
 	sp := len(sizePage)
 	if sp == 1 {
-		mapper.SqlTpl = `select top ? ${field} from ${table} ${join} ${where}`
-		mapper.Complete.Args = append(mapper.Complete.Args, sizePage[0])
+		mapper.SqlTpl = `select top (?) ${field} from ${table}${join} ${where} ${group}${having} ${order}`
+		mapper.compose.topArgs = []any{sizePage[0]}
 	}
 	if sp > 1 {
 		size := sizePage[0]
 		page := sizePage[1]
-		mapper.SqlTpl = `select top ? ${field} from (select row_number() over(${order}) as rownumber,${joinfield} from ${table} ${join} ${where}) temp_row where rownumber > ?;`
-		mapper.Complete.Args = append(mapper.Complete.Args, size, page*size-size)
+		mapper.SqlTpl = `select top (?) ${field} from (select row_number() over(${order}) as rownumber,${join_field} from ${table}${join} ${where} ${group}${having}) temp_row where rownumber > ? order by rownumber`
+		mapper.compose.topArgs = []any{size}
+		mapper.compose.limitArgs = []any{page*size - size}
 	}
 	return mapper
 }
@@ -181,31 +188,42 @@ func InitDB(switchRole bool, ReconnectNum int, ReconnectInterval time.Duration,
 		}
 	}
 
-	if Pool.PoolCount == 0 {
+	poolMu.RLock()
+	count := Pool.PoolCount
+	poolMu.RUnlock()
+	if count == 0 {
 		return errors.New("没有打开的数据库")
 	}
-	Pool.ReconnectInterval = ReconnectInterval
-	Pool.ReconnectNum = ReconnectNum
-	Pool.SwitchRole = switchRole
+	setPoolOptions(switchRole, ReconnectNum, ReconnectInterval)
 	return nil
 }
 
 func (conf Config) NewDB() (err error) {
-	conndb := ConnDB{
-		Conf: conf,
+	if !conf.Open {
+		return
+	}
+	conndb, err := conf.open()
+	if err != nil {
+		return err
 	}
+	addConn(conndb)
+	return
+}
 
-	if !conndb.Conf.Open {
-		return
+// 按配置打开连接，不加入连接池
+func (conf Config) open() (conndb *ConnDB, err error) {
+	conndb = &ConnDB{
+		Conf:   conf,
+		source: conf,
 	}
+	conndb.closed, conndb.shutdown = context.WithCancel(context.Background())
 
 	if conndb.Conf.Role == "" {
 		conndb.Conf.Role = "alone"
 	}
 
 	if conndb.Conf.Type == "" {
-		return errors.New("数据库类型不能为空")
-
+		return conndb, errors.New("数据库类型不能为空")
 	}
 
 	switch conndb.Conf.Type {
@@ -215,7 +233,11 @@ func (conf Config) NewDB() (err error) {
 		conndb.DBFunc.AddReturnId = PgsqlAddReturnId
 		conndb.drive = "postgres"
 		if conndb.Conf.Dsn == "" {
-			conndb.Conf.Dsn = fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable connect_timeout=%d", conndb.Conf.Host, conndb.Conf.Port, conndb.Conf.User, conndb.Conf.Pwd, conndb.Conf.DBName, conndb.Conf.TimeOut)
+			sslMode := conndb.Conf.SSLMode
+			if sslMode == "" {
+				sslMode = "disable"
+			}
+			conndb.Conf.Dsn = fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s connect_timeout=%d", conndb.Conf.Host, conndb.Conf.Port, conndb.Conf.User, conndb.Conf.Pwd, conndb.Conf.DBName, sslMode, conndb.Conf.TimeOut)
 		}
 	case "mysql":
 		conndb.Sign = "?"
@@ -229,7 +251,7 @@ func (conf Config) NewDB() (err error) {
 		conndb.Sign = "?"
 		conndb.DBFunc.Page = MYpage
 		conndb.DBFunc.AddReturnId = MysqlAddReturnId
-		conndb.drive = "sqlite3"
+		conndb.drive = "sqlite"
 	case "mssql":
 		conndb.Sign = "@p"
 		conndb.DBFunc.Page = MSpage
@@ -239,24 +261,17 @@ func (conf Config) NewDB() (err error) {
 			conndb.Conf.Dsn = fmt.Sprintf("sqlserver://%s:%s@%s:%d?database=%s&dial+timeout=%d&encrypt=disable&parseTime=true", conndb.Conf.User, conndb.Conf.Pwd, conndb.Conf.Host, conndb.Conf.Port, conndb.Conf.DBName, conndb.Conf.TimeOut)
 		}
 	}
+	if conndb.Conf.Driver != "" {
+		conndb.drive = conndb.Conf.Driver
+	}
 	conn, err := conndb.openSql()
 	if err != nil {
-		return err
+		return
 	}
 	conndb.DBFunc.Conn = conn
-
-	switch conndb.Conf.Role {
-	case "master":
-		Pool.Master.DBConn = append(Pool.Master.DBConn, conndb)
-		Pool.Master.PoolNum = len(Pool.Master.DBConn)
-	case "slave":
-		Pool.Slave.DBConn = append(Pool.Slave.DBConn, conndb)
-		Pool.Slave.PoolNum = len(Pool.Slave.DBConn)
-	default:
-		Pool.Alone.DBConn = append(Pool.Alone.DBConn, conndb)
-		Pool.Alone.PoolNum = len(Pool.Alone.DBConn)
+	if conndb.Conf.StmtCache > 0 {
+		conndb.stmts = newStmtCache(conndb.Conf.StmtCache)
 	}
-	Pool.PoolCount = Pool.Master.PoolNum + Pool.Slave.PoolNum + Pool.Alone.PoolNum
 	return
 }
 
@@ -287,28 +302,21 @@ func (db *ConnDB) openSql() (*sql.DB, error) {
 }
 
 func Stop() {
+	poolMu.Lock()
+	master, slave, alone := Pool.Master, Pool.Slave, Pool.Alone
+	Pool.Master, Pool.Slave, Pool.Alone = &PoolConn{}, &PoolConn{}, &PoolConn{}
 	Pool.PoolCount = 0
-	roles := []string{"master", "slave", "alone"}
-	for _, role := range roles {
-		switch role {
-		case "master":
-			close(Pool.Master.DBConn)
-			Pool.Master.PoolNum = 0
-			Pool.Master.DBConn = nil
-		case "slave":
-			close(Pool.Slave.DBConn)
-			Pool.Slave.PoolNum = 0
-			Pool.Slave.DBConn = nil
-		case "alone":
-			close(Pool.Alone.DBConn)
-			Pool.Alone.PoolNum = 0
-			Pool.Alone.DBConn = nil
-		}
-	}
+	poolMu.Unlock()
+	close(master.DBConn)
+	close(slave.DBConn)
+	close(alone.DBConn)
+	reconnects.Wait()
 }
 
-func close(db []ConnDB) {
+func close(db []*ConnDB) {
 	for _, conn := range db {
+		conn.shutdown()
+		conn.purgeStmt()
 		conn.DBFunc.Conn.Close()
 	}
 }
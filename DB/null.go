@@ -0,0 +1,55 @@
+package DB
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+)
+
+/*
+Null 可为 NULL 的值，Valid 为 false 时写入 NULL，JSON 序列化为 null
+
+	Age DB.Null[int64] `db:"age"`
+*/
+type Null[T any] sql.Null[T]
+
+// NullOf 有效值
+func NullOf[T any](v T) Null[T] {
+	return Null[T]{V: v, Valid: true}
+}
+
+func (n *Null[T]) Scan(src any) error {
+	return (*sql.Null[T])(n).Scan(src)
+}
+
+func (n Null[T]) Value() (driver.Value, error) {
+	return sql.Null[T](n).Value()
+}
+
+// Ptr 转为指针，无效时为 nil
+func (n Null[T]) Ptr() *T {
+	if !n.Valid {
+		return nil
+	}
+	return &n.V
+}
+
+func (n Null[T]) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.V)
+}
+
+func (n *Null[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*n = Null[T]{}
+		return nil
+	}
+	if err := json.Unmarshal(data, &n.V); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
@@ -0,0 +1,301 @@
+package DB
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/chris-liu-zh/qiao/tools"
+)
+
+var ErrRelation = errors.New("invalid relation")
+
+/*
+relation 关联字段的 rel 标签，关联字段不参与查询与写入
+
+	Items []OrderItem `rel:"hasMany,foreignKey=order_id"`；--order_item.order_id = order.id
+	Detail *OrderDetail `rel:"hasOne,foreignKey=order_id"`；--同上，取第一条
+	Tags []Tag `rel:"manyToMany,joinTable=order_tag,foreignKey=order_id,references=tag_id"`；--order_tag.order_id = order.id，order_tag.tag_id = tag.id
+
+	可选项：key 父表关联字段，默认 Autoincrement 字段或 id；targetKey 多对多时子表字段，默认 id；table 子表名
+*/
+type relation struct {
+	kind       string
+	table      string
+	foreignKey string
+	key        string
+	joinTable  string
+	references string
+	targetKey  string
+}
+
+// 带 rel 标签的字段为关联字段
+func isRelation(field reflect.StructField) bool {
+	return field.Tag.Get("rel") != ""
+}
+
+func parseRelation(field reflect.StructField, parent reflect.Type) (rel relation, err error) {
+	parts := strings.Split(field.Tag.Get("rel"), ",")
+	rel.kind = strings.TrimSpace(parts[0])
+	for _, p := range parts[1:] {
+		k, v, _ := strings.Cut(p, "=")
+		switch v = strings.TrimSpace(v); strings.TrimSpace(k) {
+		case "foreignKey":
+			rel.foreignKey = v
+		case "key":
+			rel.key = v
+		case "joinTable":
+			rel.joinTable = v
+		case "references":
+			rel.references = v
+		case "targetKey":
+			rel.targetKey = v
+		case "table":
+			rel.table = v
+		}
+	}
+	child := field.Type
+	switch rel.kind {
+	case "hasOne":
+		if child.Kind() == reflect.Pointer {
+			child = child.Elem()
+		}
+	case "hasMany", "manyToMany":
+		if child.Kind() != reflect.Slice {
+			return rel, fmt.Errorf("%w: %s %s must be a slice", ErrRelation, rel.kind, field.Name)
+		}
+		if child = child.Elem(); child.Kind() == reflect.Pointer {
+			child = child.Elem()
+		}
+	default:
+		return rel, fmt.Errorf("%w: %s unsupported kind %q", ErrRelation, field.Name, rel.kind)
+	}
+	if child.Kind() != reflect.Struct || rel.foreignKey == "" {
+		return rel, fmt.Errorf("%w: %s requires a struct type and foreignKey", ErrRelation, field.Name)
+	}
+	if rel.kind == "manyToMany" && (rel.joinTable == "" || rel.references == "") {
+		return rel, fmt.Errorf("%w: %s requires joinTable and references", ErrRelation, field.Name)
+	}
+	if rel.table == "" {
+		rel.table = tools.CamelCaseToUdnderscore(child.Name())
+	}
+	if rel.key == "" {
+		rel.key = primaryKey(parent)
+	}
+	if rel.targetKey == "" {
+		rel.targetKey = "id"
+	}
+	return
+}
+
+// 结构体的 Autoincrement 字段列名，没有时为 id
+func primaryKey(t reflect.Type) string {
+	for i := range t.NumField() {
+		tags := strings.Split(t.Field(i).Tag.Get("db"), ";")
+		if tagOption(tags, "Autoincrement") {
+			if c := getColumn(tags); c != "" {
+				return c
+			}
+			return tools.CamelCaseToUdnderscore(t.Field(i).Name)
+		}
+	}
+	return "id"
+}
+
+// 按列名查找字段位置
+func columnIndex(t reflect.Type, column string) ([]int, bool) {
+	for _, c := range readColumns(t, "", nil) {
+		if c.column == column {
+			return c.index, true
+		}
+	}
+	return nil, false
+}
+
+/*
+Preload 查询后加载关联字段，每个关联执行一次 in 查询，对 Get / GetList 生效
+
+	@fields string；--带 rel 标签的字段名
+
+	DB.QiaoDB().Preload("Items", "Tags").Find(DB.Gt("id", 10)).GetList(&orders)
+*/
+func (mapper *Mapper) Preload(fields ...string) *Mapper {
+	mapper.preload = append(mapper.preload, fields...)
+	return mapper
+}
+
+// 加载关联，parents 为结构体切片
+func (mapper *Mapper) loadRelations(parents reflect.Value) error {
+	if parents.Len() == 0 {
+		return nil
+	}
+	parentType := parents.Type().Elem()
+	for _, name := range mapper.preload {
+		field, ok := parentType.FieldByName(name)
+		if !ok || !isRelation(field) {
+			return fmt.Errorf("%w: %s has no relation field %s", ErrRelation, parentType.Name(), name)
+		}
+		rel, err := parseRelation(field, parentType)
+		if err != nil {
+			return err
+		}
+		keyIndex, ok := columnIndex(parentType, rel.key)
+		if !ok {
+			return fmt.Errorf("%w: %s has no key column %s", ErrRelation, parentType.Name(), rel.key)
+		}
+		var keys []any
+		seen := make(map[string]bool)
+		for i := range parents.Len() {
+			k := parents.Index(i).FieldByIndex(keyIndex).Interface()
+			if s := fmt.Sprint(k); !seen[s] {
+				seen[s] = true
+				keys = append(keys, k)
+			}
+		}
+		var children map[string][]reflect.Value
+		if rel.kind == "manyToMany" {
+			children, err = mapper.loadManyToMany(field, rel, keys)
+		} else {
+			children, err = mapper.loadChildren(field, rel, rel.foreignKey, keys)
+		}
+		if err != nil {
+			return err
+		}
+		for i := range parents.Len() {
+			parent := parents.Index(i)
+			assignRelation(parent.FieldByIndex(field.Index), children[fmt.Sprint(parent.FieldByIndex(keyIndex).Interface())])
+		}
+	}
+	return nil
+}
+
+// 子表数据按 column 的值分组
+func (mapper *Mapper) loadChildren(field reflect.StructField, rel relation, column string, keys []any) (map[string][]reflect.Value, error) {
+	childType := field.Type
+	for childType.Kind() == reflect.Pointer || childType.Kind() == reflect.Slice {
+		childType = childType.Elem()
+	}
+	index, ok := columnIndex(childType, column)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s has no column %s", ErrRelation, childType.Name(), column)
+	}
+	groups := make(map[string][]reflect.Value)
+	for _, chunk := range mapper.chunkKeys(keys) {
+		list := reflect.New(reflect.SliceOf(childType))
+		err := QiaoDB(OptionsRole(mapper.Role), OptionsDebug(mapper.Complete.Debug)).Context(mapper.context()).
+			Table(rel.table).Find(In(column, chunk...)).GetList(list.Interface())
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Elem().Len() {
+			child := list.Elem().Index(i)
+			k := fmt.Sprint(child.FieldByIndex(index).Interface())
+			groups[k] = append(groups[k], child)
+		}
+	}
+	return groups, nil
+}
+
+// 先查中间表得到父子对应关系，再按子表主键查询
+func (mapper *Mapper) loadManyToMany(field reflect.StructField, rel relation, keys []any) (map[string][]reflect.Value, error) {
+	db := mapper.Read()
+	if db == nil {
+		return nil, ErrNoConn
+	}
+	column, tenant, scoped, err := mapper.tenantOf(rel.joinTable)
+	if err != nil {
+		return nil, err
+	}
+	var pairs [][2]any
+	var targets []any
+	seen := make(map[string]bool)
+	for _, chunk := range mapper.chunkKeys(keys) {
+		cond, args := In(rel.foreignKey, chunk...).Build()
+		if scoped {
+			cond, args = cond+" and "+column+" = ?", append(args, tenant)
+		}
+		rows, err := db.QueryContext(mapper.context(), fmt.Sprintf("select %s,%s from %s where %s", rel.foreignKey, rel.references, rel.joinTable, cond), args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var pair [2]any
+			if err = rows.Scan(&pair[0], &pair[1]); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			pair[0], pair[1] = scanKey(pair[0]), scanKey(pair[1])
+			pairs = append(pairs, pair)
+			if s := fmt.Sprint(pair[1]); !seen[s] {
+				seen[s] = true
+				targets = append(targets, pair[1])
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	byTarget, err := mapper.loadChildren(field, rel, rel.targetKey, targets)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string][]reflect.Value)
+	for _, pair := range pairs {
+		k := fmt.Sprint(pair[0])
+		groups[k] = append(groups[k], byTarget[fmt.Sprint(pair[1])]...)
+	}
+	return groups, nil
+}
+
+// 驱动返回的 []byte 键转为字符串，便于与结构体中的值比较
+func scanKey(v any) any {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return v
+}
+
+// 按数据库参数上限拆分 in 查询，mssql 单条语句最多 2100 个参数
+func (mapper *Mapper) chunkKeys(keys []any) (chunks [][]any) {
+	size := 10000
+	if db := mapper.Read(); db != nil && db.Conf.Type == "mssql" {
+		size = 2000
+	}
+	for len(keys) > size {
+		chunks = append(chunks, keys[:size])
+		keys = keys[size:]
+	}
+	return append(chunks, keys)
+}
+
+func assignRelation(field reflect.Value, children []reflect.Value) {
+	switch t := field.Type(); t.Kind() {
+	case reflect.Slice:
+		list := reflect.MakeSlice(t, 0, len(children))
+		for _, c := range children {
+			if t.Elem().Kind() == reflect.Pointer {
+				p := reflect.New(c.Type())
+				p.Elem().Set(c)
+				c = p
+			}
+			list = reflect.Append(list, c)
+		}
+		field.Set(list)
+	case reflect.Pointer:
+		if len(children) > 0 {
+			p := reflect.New(t.Elem())
+			p.Elem().Set(children[0])
+			field.Set(p)
+		}
+	default:
+		if len(children) > 0 {
+			field.Set(children[0])
+		}
+	}
+}
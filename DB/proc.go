@@ -22,6 +22,7 @@ type proc struct {
 	m       *Mapper
 }
 
+// Deprecated: Set 直接拼接参数值，存在注入风险，请使用 Call
 func (mapper *Mapper) Proc2(name string) *proc {
 	p := &proc{
 		name: name,
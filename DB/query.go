@@ -8,26 +8,33 @@
 package DB
 
 import (
+	"context"
 	"database/sql"
 )
 
 func (db *ConnDB) Query(sqlStr string, args ...any) (rows *sql.Rows, err error) {
+	return db.QueryContext(context.Background(), sqlStr, args...)
+}
+
+// QueryContext 查询，ctx 传递给拦截器与驱动
+func (db *ConnDB) QueryContext(ctx context.Context, sqlStr string, args ...any) (rows *sql.Rows, err error) {
 	if db == nil {
 		return nil, ErrNoConn
 	}
 	query := Replace(sqlStr, "?", db.Sign)
 	db.log("Query", query, args).logDEBUG()
-	if rows, err = db.DBFunc.Conn.Query(query, args...); err == nil {
+	if rows, err = db.query(ctx, query, args...); err == nil {
 		return
 	}
 	db.log("Query error", query, args).logERROR(err)
 	role := db.Conf.Role
-	if opErr := db.checkOpError(err); opErr {
-		if db = GetNewPool(role); db == nil {
-			return nil, ErrNoConn
-		}
+	if !db.checkOpError(err) {
+		return
 	}
-	if rows, err = db.DBFunc.Conn.Query(query, args...); err == nil {
+	if db = GetNewPool(role); db == nil {
+		return nil, ErrNoConn
+	}
+	if rows, err = db.query(ctx, query, args...); err == nil {
 		return
 	}
 	db.log("Query error", query, args).logERROR(err)
@@ -35,34 +42,45 @@ func (db *ConnDB) Query(sqlStr string, args ...any) (rows *sql.Rows, err error)
 }
 
 func (db *ConnDB) Count(sqlStr string, args ...any) (RowsCount int, err error) {
+	return db.CountContext(context.Background(), sqlStr, args...)
+}
+
+// CountContext 查询单个计数值
+func (db *ConnDB) CountContext(ctx context.Context, sqlStr string, args ...any) (RowsCount int, err error) {
+	err = db.scanRow(ctx, "Count", sqlStr, args, &RowsCount)
+	return
+}
+
+// 查询单行并扫描到 dest，日志与故障切换同 QueryContext
+func (db *ConnDB) scanRow(ctx context.Context, msg, sqlStr string, args []any, dest ...any) (err error) {
 	if db == nil {
-		return 0, ErrNoConn
+		return ErrNoConn
 	}
-	RowsCount = 0
 	query := Replace(sqlStr, "?", db.Sign)
-	db.log("Count", query, args).logDEBUG()
-	if err = db.DBFunc.Conn.QueryRow(query, args...).Scan(&RowsCount); err == nil {
+	db.log(msg, query, args).logDEBUG()
+	if err = db.queryRowScan(ctx, query, args, dest...); err == nil {
 		return
 	}
-	db.log("Count error", query, args).logERROR(err)
+	db.log(msg+" error", query, args).logERROR(err)
 	role := db.Conf.Role
-	if opErr := db.checkOpError(err); opErr {
-		if db = GetNewPool(role); db == nil {
-			return 0, ErrNoConn
-		}
+	if !db.checkOpError(err) {
+		return
+	}
+	if db = GetNewPool(role); db == nil {
+		return ErrNoConn
 	}
-	if err = db.DBFunc.Conn.QueryRow(query, args...).Scan(&RowsCount); err == nil {
+	if err = db.queryRowScan(ctx, query, args, dest...); err == nil {
 		return
 	}
-	db.log("Count error", query, args).logERROR(err)
+	db.log(msg+" error", query, args).logERROR(err)
 	return
 }
 
 // Query 直接查询sql语句
 func (mapper *Mapper) Query(sql string, args ...any) (*Mapper, error) {
-	mapper.Complete = SqlComplete{Sql: sql, Args: args}
+	mapper.Complete, mapper.raw = SqlComplete{Sql: sql, Args: args}, true
 	var err error
-	if mapper.sqlRows, err = mapper.Read().Query(sql, args...); err != nil {
+	if mapper.sqlRows, err = mapper.Read().QueryContext(mapper.context(), sql, args...); err != nil {
 		mapper.log("Query error").logERROR(err)
 		return nil, err
 	}
@@ -73,8 +91,8 @@ func (mapper *Mapper) Query(sql string, args ...any) (*Mapper, error) {
 // QueryRow 直接查询sql语句
 func (mapper *Mapper) QueryRow(sql string, args ...any) (*Mapper, error) {
 	var err error
-	mapper.Complete = SqlComplete{Sql: sql, Args: args}
-	if mapper.sqlRows, err = mapper.Read().Query(sql, args...); err != nil {
+	mapper.Complete, mapper.raw = SqlComplete{Sql: sql, Args: args}, true
+	if mapper.sqlRows, err = mapper.Read().QueryContext(mapper.context(), sql, args...); err != nil {
 		mapper.log("Query error").logERROR(err)
 		return nil, err
 	}
@@ -0,0 +1,221 @@
+package DB
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ReloadDrain 重新加载后被替换的连接延迟关闭的时间，等待进行中的查询结束
+var ReloadDrain = 30 * time.Second
+
+// 保护 Pool 中 Master / Slave / Alone 的替换，连接池只整体替换不原地修改
+var poolMu sync.RWMutex
+
+// 串行化 Reload 的对比、打开与替换，避免并发加载基于同一连接池替换，落败一方打开的连接无法关闭
+var reloadMu sync.Mutex
+
+func rolePool(role string) *PoolConn {
+	poolMu.RLock()
+	defer poolMu.RUnlock()
+	switch role {
+	case "master":
+		return Pool.Master
+	case "slave":
+		return Pool.Slave
+	default:
+		return Pool.Alone
+	}
+}
+
+// 复制连接池并加入连接后整体替换
+func addConn(conndb *ConnDB) {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	switch conndb.Conf.Role {
+	case "master":
+		Pool.Master = newPoolConn(append(clonePool(Pool.Master), conndb))
+	case "slave":
+		Pool.Slave = newPoolConn(append(clonePool(Pool.Slave), conndb))
+	default:
+		Pool.Alone = newPoolConn(append(clonePool(Pool.Alone), conndb))
+	}
+	Pool.PoolCount = Pool.Master.PoolNum + Pool.Slave.PoolNum + Pool.Alone.PoolNum
+}
+
+func clonePool(p *PoolConn) []*ConnDB {
+	if p == nil {
+		return nil
+	}
+	return append([]*ConnDB(nil), p.DBConn...)
+}
+
+func newPoolConn(conns []*ConnDB) *PoolConn {
+	return &PoolConn{PoolNum: len(conns), DBConn: conns}
+}
+
+/*
+Reload 按 Config.ID 对比新配置与当前连接池并整体替换
+
+	Config.ID 必须非零且不重复，当前连接池中的连接同样如此；配置未变的连接保留（同一 *ConnDB，状态共享）；新增或变更的连接先打开，任一打开失败时放弃本次替换；
+	移除或变更前的连接在 ReloadDrain 后关闭，不影响进行中的查询；多次调用串行执行
+*/
+func Reload(conf ...Config) error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	return reload(conf...)
+}
+
+func reload(conf ...Config) error {
+	ids := make(map[int]bool)
+	for _, c := range conf {
+		if !c.Open {
+			continue
+		}
+		if c.ID == 0 || ids[c.ID] {
+			return fmt.Errorf("reload: ID %d is zero or duplicate", c.ID)
+		}
+		ids[c.ID] = true
+	}
+
+	poolMu.RLock()
+	current := make(map[int]*ConnDB)
+	for _, p := range []*PoolConn{Pool.Master, Pool.Slave, Pool.Alone} {
+		for _, c := range p.DBConn {
+			if _, ok := current[c.Conf.ID]; ok || c.Conf.ID == 0 {
+				poolMu.RUnlock()
+				// 无法对应到新配置，替换后将无法关闭
+				return fmt.Errorf("reload: current pool has zero or duplicate ID %d, set Config.ID before InitDB", c.Conf.ID)
+			}
+			current[c.Conf.ID] = c
+		}
+	}
+	poolMu.RUnlock()
+
+	var master, slave, alone, opened []*ConnDB
+	kept := make(map[int]bool)
+	for _, c := range conf {
+		if !c.Open {
+			continue
+		}
+		conndb, ok := current[c.ID]
+		if ok && conndb.source == c {
+			kept[c.ID] = true
+		} else {
+			var err error
+			if conndb, err = c.open(); err != nil {
+				close(opened)
+				return fmt.Errorf("reload: ID %d: %w", c.ID, err)
+			}
+			opened = append(opened, conndb)
+		}
+		switch conndb.Conf.Role {
+		case "master":
+			master = append(master, conndb)
+		case "slave":
+			slave = append(slave, conndb)
+		default:
+			alone = append(alone, conndb)
+		}
+	}
+	if len(master)+len(slave)+len(alone) == 0 {
+		close(opened)
+		return errors.New("reload: 没有打开的数据库")
+	}
+
+	poolMu.Lock()
+	Pool.Master, Pool.Slave, Pool.Alone = newPoolConn(master), newPoolConn(slave), newPoolConn(alone)
+	Pool.PoolCount = len(master) + len(slave) + len(alone)
+	poolMu.Unlock()
+
+	var removed []*ConnDB
+	for id, c := range current {
+		if !kept[id] {
+			removed = append(removed, c)
+		}
+	}
+	if len(removed) > 0 {
+		time.AfterFunc(ReloadDrain, func() { close(removed) })
+	}
+	return nil
+}
+
+// ReloadFile 读取配置文件并重新加载连接池
+func ReloadFile(path string) error {
+	fc, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	if err = reload(fc.Databases...); err != nil {
+		return err
+	}
+	setPoolOptions(fc.SwitchRole, fc.ReconnectNum, fc.interval)
+	return nil
+}
+
+func setPoolOptions(switchRole bool, reconnectNum int, reconnectInterval time.Duration) {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	Pool.SwitchRole = switchRole
+	Pool.ReconnectNum = reconnectNum
+	Pool.ReconnectInterval = reconnectInterval
+}
+
+// 读取主从切换与重连设置
+func poolOptions() (switchRole bool, reconnectNum int, reconnectInterval time.Duration) {
+	poolMu.RLock()
+	defer poolMu.RUnlock()
+	return Pool.SwitchRole, Pool.ReconnectNum, Pool.ReconnectInterval
+}
+
+/*
+WatchConfig 定时检查配置文件，修改后重新加载
+
+	@interval time.Duration；--检查间隔
+	@onError func(error)；--加载失败时回调，可为 nil；失败时保留当前连接池
+	返回停止监听的函数，返回时进行中的加载已结束
+*/
+func WatchConfig(path string, interval time.Duration, onError func(error)) (stop func()) {
+	var modTime time.Time
+	if info, err := os.Stat(path); err == nil {
+		modTime = info.ModTime()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ticker := time.NewTicker(interval)
+	// 包内 close 已被占用，以带缓冲的 channel 通知退出
+	done := make(chan struct{}, 1)
+	go func() {
+		defer func() { done <- struct{}{} }()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			info, err := os.Stat(path)
+			if err != nil || info.ModTime().Equal(modTime) {
+				continue
+			}
+			modTime = info.ModTime()
+			if err = ReloadFile(path); err != nil {
+				(&sqlLog{Message: "reload config error", Sqlstr: path}).logERROR(err)
+				if onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel()
+			<-done
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package DB
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/chris-liu-zh/qiao/tools"
+)
+
+var (
+	ErrNoResultSet = errors.New("no more result sets")
+	ErrNoColumns   = errors.New("result set has no columns")
+	scannerType    = reflect.TypeFor[sql.Scanner]()
+	mapType        = reflect.TypeFor[map[string]any]()
+)
+
+/*
+ResultSets 依次读取多个结果集，读取完后关闭 rows
+
+	@dests 每个结果集对应一个接收对象：
+	*[]struct；--多行结构体
+	*[]map[string]any；--多行map
+	*struct、*map[string]any；--单行
+	其他指针；--单个值，取第一行第一列
+	nil；--跳过该结果集
+*/
+func ResultSets(rows *sql.Rows, dests ...any) (err error) {
+	if rows == nil {
+		return ErrNoResultSet
+	}
+	defer tools.DeferErr(&err, rows.Close)
+	for i, dest := range dests {
+		if i > 0 && !rows.NextResultSet() {
+			if err = rows.Err(); err != nil {
+				return
+			}
+			return fmt.Errorf("result set %d: %w", i+1, ErrNoResultSet)
+		}
+		if dest == nil {
+			continue
+		}
+		if err = scanResultSet(rows, dest); err != nil {
+			return fmt.Errorf("result set %d: %w", i+1, err)
+		}
+	}
+	return
+}
+
+// ResultSets 读取 Query / QueryRow 得到的多个结果集
+func (mapper *Mapper) ResultSets(dests ...any) error {
+	rows := mapper.sqlRows
+	mapper.sqlRows = nil
+	if err := ResultSets(rows, dests...); err != nil {
+		mapper.log("scan result sets error").logERROR(err)
+		return err
+	}
+	return nil
+}
+
+func scanResultSet(rows *sql.Rows, dest any) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return ErrNotPtr
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	elem := v.Elem()
+	switch {
+	case elem.Kind() == reflect.Slice && elem.Type().Elem() == mapType:
+		for rows.Next() {
+			row, err := scanMapRow(rows, columns)
+			if err != nil {
+				return err
+			}
+			elem.Set(reflect.Append(elem, reflect.ValueOf(row)))
+		}
+	case elem.Kind() == reflect.Slice && isStructRow(elem.Type().Elem()):
+		fields, err := matchColumns(elem.Type().Elem(), columns)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			item := reflect.New(elem.Type().Elem()).Elem()
+			if err = rows.Scan(fieldPointers(item, fields)...); err != nil {
+				return err
+			}
+			elem.Set(reflect.Append(elem, item))
+		}
+	case elem.Type() == mapType:
+		if !rows.Next() {
+			return noRows(rows)
+		}
+		row, err := scanMapRow(rows, columns)
+		if err != nil {
+			return err
+		}
+		elem.Set(reflect.ValueOf(row))
+	case isStructRow(elem.Type()):
+		if !rows.Next() {
+			return noRows(rows)
+		}
+		fields, err := matchColumns(elem.Type(), columns)
+		if err != nil {
+			return err
+		}
+		return rows.Scan(fieldPointers(elem, fields)...)
+	default:
+		if len(columns) == 0 {
+			return ErrNoColumns
+		}
+		if !rows.Next() {
+			return noRows(rows)
+		}
+		// 只取第一列，其余列丢弃
+		pointer := make([]any, len(columns))
+		pointer[0] = dest
+		for i := 1; i < len(columns); i++ {
+			pointer[i] = new(any)
+		}
+		return rows.Scan(pointer...)
+	}
+	return rows.Err()
+}
+
+// 结构体按行读取；time.Time 及实现 sql.Scanner 的结构体按单值读取
+func isStructRow(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t == reflect.TypeFor[time.Time]() {
+		return false
+	}
+	return !reflect.PointerTo(t).Implements(scannerType)
+}
+
+func scanMapRow(rows *sql.Rows, columns []string) (map[string]any, error) {
+	pointer := make([]any, len(columns))
+	for i := range pointer {
+		pointer[i] = new(any)
+	}
+	if err := rows.Scan(pointer...); err != nil {
+		return nil, err
+	}
+	row := make(map[string]any, len(columns))
+	for i := range columns {
+		row[columns[i]] = *pointer[i].(*any)
+	}
+	return row, nil
+}
+
+func noRows(rows *sql.Rows) error {
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return sql.ErrNoRows
+}
@@ -0,0 +1,110 @@
+package DB
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+	sqlite "github.com/glebarez/go-sqlite"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+/*
+RetryPolicy 死锁、序列化失败的重试策略
+
+	单条语句（非事务）与 Transaction 闭包事务按此重试，Begin 手动事务中的语句不重试
+*/
+type RetryPolicy struct {
+	MaxAttempts int                                  // 最多执行次数，小于等于 1 时不重试
+	Backoff     time.Duration                        // 首次重试前的等待，之后每次翻倍，实际等待在 [d/2, d] 间随机
+	MaxBackoff  time.Duration                        // 等待上限，为 0 时不限制
+	Retryable   func(dialect string, err error) bool // 判断错误是否可重试，为 nil 时使用 IsRetryable
+}
+
+var retryPolicy atomic.Pointer[RetryPolicy]
+
+func init() {
+	SetRetry(RetryPolicy{MaxAttempts: 3, Backoff: 50 * time.Millisecond, MaxBackoff: time.Second})
+}
+
+// SetRetry 设置全局重试策略，RetryPolicy{} 关闭重试；可与执行中的语句并发调用
+func SetRetry(policy RetryPolicy) {
+	retryPolicy.Store(&policy)
+}
+
+/*
+IsRetryable 默认的可重试错误判断
+
+	mysql: 1213 死锁
+	mssql: 1205 死锁
+	pgsql: 40001 序列化失败，40P01 死锁
+	sqlite: SQLITE_BUSY / SQLITE_LOCKED
+*/
+func IsRetryable(dialect string, err error) bool {
+	switch dialect {
+	case "mysql":
+		var e *mysql.MySQLError
+		return errors.As(err, &e) && e.Number == 1213
+	case "mssql":
+		var e mssql.Error
+		return errors.As(err, &e) && e.Number == 1205
+	case "pgsql":
+		var e *pq.Error
+		return errors.As(err, &e) && (e.Code == "40001" || e.Code == "40P01")
+	case "sqlite":
+		var e *sqlite.Error
+		return errors.As(err, &e) && (e.Code()&0xff == 5 || e.Code()&0xff == 6)
+	}
+	return false
+}
+
+func (p RetryPolicy) retryable(dialect string, err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(dialect, err)
+	}
+	return IsRetryable(dialect, err)
+}
+
+// 第 attempt 次失败后的等待，ctx 结束时提前返回
+func (p RetryPolicy) wait(ctx context.Context, attempt int) error {
+	d := p.Backoff << (attempt - 1)
+	if p.MaxBackoff > 0 && (d > p.MaxBackoff || d <= 0) {
+		d = p.MaxBackoff
+	}
+	if d > 0 {
+		d = d/2 + rand.N(d/2+1)
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+/*
+按重试策略执行 fn，每次重试记录 WARNING 日志
+
+	@dialect string；--数据库类型，传给可重试判断
+	@title string；--连接标题，用于日志
+*/
+func withRetry(ctx context.Context, dialect, title, sqlStr string, args []any, fn func() error) (err error) {
+	policy := *retryPolicy.Load()
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || attempt >= policy.MaxAttempts || !policy.retryable(dialect, err) {
+			return
+		}
+		info := &sqlLog{Message: fmt.Sprintf("retry %d/%d: %v", attempt, policy.MaxAttempts-1, err), Title: title, Sqlstr: sqlStr, Args: args}
+		info.logWARNING()
+		if waitErr := policy.wait(ctx, attempt); waitErr != nil {
+			return errors.Join(err, waitErr)
+		}
+	}
+}
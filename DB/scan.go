@@ -4,19 +4,18 @@ import (
 	"database/sql"
 	"errors"
 	"reflect"
-	"strings"
-
-	"github.com/chris-liu-zh/qiao/tools"
 )
 
 var (
 	ErrNotPtr    = errors.New("type is not reflect.Pointer")
 	ErrNotStruct = errors.New("type is not reflect.Struct")
 	ErrNotSlice  = errors.New("type is not reflect.Slice")
+
+	ErrUnmatchedColumn = errors.New("result column has no matching struct field")
 )
 
 func (mapper *Mapper) ScanRowMap() (row map[string]any, err error) {
-	defer tools.DeferErr(&err, mapper.sqlRows.Close)
+	defer mapper.closeRows(&err)
 	columns, err := mapper.sqlRows.Columns()
 	if err != nil {
 		return
@@ -44,14 +43,7 @@ func (mapper *Mapper) ScanRowMap() (row map[string]any, err error) {
 }
 
 func (mapper *Mapper) ScanRowStruct(_struct any) (err error) {
-	defer tools.DeferErr(&err, mapper.sqlRows.Close)
-	columns, err := mapper.sqlRows.Columns()
-	if err != nil {
-		return
-	}
-	length := len(columns)
-	pointer := make([]any, length)
-
+	defer mapper.closeRows(&err)
 	ReflectV := reflect.ValueOf(_struct)
 	if ReflectV.Kind() != reflect.Pointer {
 		return ErrNotPtr
@@ -60,13 +52,15 @@ func (mapper *Mapper) ScanRowStruct(_struct any) (err error) {
 	if elem.Kind() != reflect.Struct {
 		return ErrNotStruct
 	}
-	reflectT := reflect.TypeOf(_struct)
-	if reflectT.Kind() != reflect.Pointer {
-		return ErrNotPtr
+	columns, err := mapper.sqlRows.Columns()
+	if err != nil {
+		return
 	}
-	for i := range length {
-		pointer[i] = elem.FieldByName(reflectT.Elem().Field(i).Name).Addr().Interface()
+	fields, err := matchColumns(elem.Type(), columns)
+	if err != nil {
+		return
 	}
+	pointer := fieldPointers(elem, fields)
 
 	if !mapper.sqlRows.Next() {
 		if err = mapper.sqlRows.Err(); err != nil {
@@ -81,7 +75,7 @@ func (mapper *Mapper) ScanRowStruct(_struct any) (err error) {
 }
 
 func (mapper *Mapper) scanListMap() (list []map[string]any, err error) {
-	defer tools.DeferErr(&err, mapper.sqlRows.Close)
+	defer mapper.closeRows(&err)
 	columns, err := mapper.sqlRows.Columns()
 	if err != nil {
 		return
@@ -104,29 +98,24 @@ func (mapper *Mapper) scanListMap() (list []map[string]any, err error) {
 }
 
 func (mapper *Mapper) scanListStruct(_struct any) (err error) {
-	defer tools.DeferErr(&err, mapper.sqlRows.Close)
+	defer mapper.closeRows(&err)
 	reflectT := reflect.TypeOf(_struct)
 	if reflectT.Kind() != reflect.Pointer {
 		return ErrNotPtr
 	}
 	sliceVal := reflect.Indirect(reflect.ValueOf(_struct))
-	sliceItem := reflect.New(reflectT.Elem().Elem()).Elem()
+	itemType := reflectT.Elem().Elem()
 	columns, err := mapper.sqlRows.Columns()
 	if err != nil {
 		return
 	}
-	length := len(columns)
+	fields, err := matchColumns(itemType, columns)
+	if err != nil {
+		return
+	}
 	for mapper.sqlRows.Next() {
-		pointer := make([]any, 0, length)
-		for i := range reflectT.Elem().Elem().NumField() {
-			columns := strings.Split(reflectT.Elem().Elem().Field(i).Tag.Get("db"), ";")
-			if ReadOnlyField(columns) {
-				fieldVal := sliceItem.FieldByName(reflectT.Elem().Elem().Field(i).Name)
-				pointer = append(pointer, fieldVal.Addr().Interface())
-			}
-		}
-
-		if err = mapper.sqlRows.Scan(pointer...); err != nil {
+		sliceItem := reflect.New(itemType).Elem()
+		if err = mapper.sqlRows.Scan(fieldPointers(sliceItem, fields)...); err != nil {
 			return err
 		}
 		sliceVal.Set(reflect.Append(sliceVal, sliceItem))
@@ -10,12 +10,11 @@ package DB
 import (
 	"fmt"
 	"reflect"
-	"strings"
 
 	"github.com/chris-liu-zh/qiao/tools"
 )
 
-const Select = "select ${field} from ${table} ${where} ${order} ${group}"
+const Select = "select ${field} from ${table}${join} ${where} ${group}${having} ${order}"
 
 /*
 查询最大值
@@ -38,7 +37,7 @@ func (mapper *Mapper) Max(_struct any, field string) (max int, err error) {
 		return
 	}
 	mapper.debug("Max")
-	if max, err = mapper.Read().Count(mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
+	if max, err = mapper.Read().CountContext(mapper.context(), mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
 		return
 	}
 	return
@@ -51,7 +50,7 @@ func (mapper *Mapper) GetRowMap() (data map[string]any, err error) {
 		return nil, err
 	}
 	mapper.debug("GetRowMap")
-	if mapper.sqlRows, err = mapper.Read().Query(mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
+	if mapper.sqlRows, err = mapper.Read().QueryContext(mapper.context(), mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
 		return nil, err
 	}
 	if data, err = mapper.ScanRowMap(); err != nil {
@@ -68,7 +67,7 @@ func (mapper *Mapper) GetListMap() (list []map[string]any, err error) {
 		return
 	}
 	mapper.debug("GetListMap")
-	if mapper.sqlRows, err = mapper.Read().Query(mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
+	if mapper.sqlRows, err = mapper.Read().QueryContext(mapper.context(), mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
 		return
 	}
 	if list, err = mapper.scanListMap(); err != nil {
@@ -92,13 +91,22 @@ func (mapper *Mapper) Get(_struct any) (err error) {
 		return
 	}
 	mapper.debug("Get")
-	if mapper.sqlRows, err = mapper.Read().Query(mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
+	if mapper.sqlRows, err = mapper.Read().QueryContext(mapper.context(), mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
 		return
 	}
 	if err = mapper.ScanRowStruct(_struct); err != nil {
 		mapper.log("scan row struct error").logERROR(err)
 		return
 	}
+	if len(mapper.preload) > 0 {
+		// 单条结果按长度为 1 的切片加载关联后写回
+		one := reflect.MakeSlice(reflect.SliceOf(elem.Type()), 1, 1)
+		one.Index(0).Set(elem)
+		if err = mapper.loadRelations(one); err != nil {
+			return
+		}
+		elem.Set(one.Index(0))
+	}
 	return
 }
 
@@ -121,31 +129,23 @@ func (mapper *Mapper) GetList(_struct any) (err error) {
 		mapper.Debris.table = tools.CamelCaseToUdnderscore(elem.Name())
 	}
 	if mapper.Debris.field == "" {
-		for i := range elem.NumField() {
-			columns := strings.Split(elem.Field(i).Tag.Get("db"), ";")
-			if ReadOnlyField(columns) {
-				if c := getColumn(columns); c != "" {
-					mapper.Debris.field += c + `,`
-				} else {
-					mapper.Debris.field += tools.CamelCaseToUdnderscore(elem.Field(i).Name) + `,`
-				}
-			}
-		}
+		mapper.Debris.field = selectFields(elem)
 	}
-	mapper.Debris.field = strings.TrimRight(mapper.Debris.field, ",")
 	if mapper.Complete.Sql, err = mapper.getSql(); err != nil {
 		mapper.log("get sql error").logERROR(err)
 		return
 	}
 	mapper.debug("GetList")
-	if mapper.sqlRows, err = mapper.Read().Query(mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
+	if mapper.sqlRows, err = mapper.Read().QueryContext(mapper.context(), mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
 		return
 	}
 	if err = mapper.scanListStruct(_struct); err != nil {
 		mapper.log("scan list struct error").logERROR(err)
 		return
 	}
-
+	if len(mapper.preload) > 0 {
+		return mapper.loadRelations(reflect.ValueOf(_struct).Elem())
+	}
 	return
 }
 
@@ -158,15 +158,15 @@ func (mapper *Mapper) Count(_struct any, index string) (count int, err error) {
 	if elem.Kind() != reflect.Struct {
 		return 0, ErrNotStruct
 	}
-	if mapper, err = mapper.getMapper(elem); err != nil {
-		return 0, err
-	}
 	if index == "" {
 		index = "*"
 	}
-	mapper.Complete.Sql = fmt.Sprintf("select count(%s) from(%s) a", index, mapper.Complete.Sql)
+	mapper.compose.count = index
+	if mapper, err = mapper.getMapper(elem); err != nil {
+		return 0, err
+	}
 	mapper.debug("Count")
-	if count, err = mapper.Read().Count(mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
+	if count, err = mapper.Read().CountContext(mapper.context(), mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
 		return
 	}
 	return
@@ -177,18 +177,8 @@ func (mapper *Mapper) getMapper(elem reflect.Value) (*Mapper, error) {
 		mapper.Debris.table = tools.CamelCaseToUdnderscore(elem.Type().Name())
 	}
 	if mapper.Debris.field == "" {
-		for i := range elem.NumField() {
-			fields := strings.Split(elem.Type().Field(i).Tag.Get("db"), ";")
-			if ReadOnlyField(fields) {
-				if c := getColumn(fields); c != "" {
-					mapper.Debris.field += c + `,`
-				} else {
-					mapper.Debris.field += tools.CamelCaseToUdnderscore(elem.Type().Field(i).Name) + `,`
-				}
-			}
-		}
+		mapper.Debris.field = selectFields(elem.Type())
 	}
-	mapper.Debris.field = strings.TrimRight(mapper.Debris.field, ",")
 	var err error
 	if mapper.Complete.Sql, err = mapper.getSql(); err != nil {
 		mapper.log("get sql error").logERROR(err)
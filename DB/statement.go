@@ -0,0 +1,246 @@
+package DB
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var ErrStatementNotFound = errors.New("statement not found")
+
+var (
+	statementMu sync.RWMutex
+	statements  = make(map[string]*statement)
+)
+
+type statement struct {
+	name  string
+	nodes []stmtNode
+}
+
+/*
+LoadStatements 从文件系统加载命名语句，递归读取 .sql 与 .xml 文件，可传入 embed.FS
+
+	.sql 文件以文件名为命名空间，每条语句以 -- name: 开头：
+
+		-- name: findActive
+		select id,name from user
+		<where>
+			status = 1
+			<if test="name != ''">and name like #{name}</if>
+		</where>
+
+	.xml 文件为 MyBatis 格式，命名空间为 mapper 的 namespace，未设置时使用文件名：
+
+		<mapper namespace="user">
+			<select id="findActive">select id,name from user where id in
+				<foreach collection="ids" item="id" open="(" separator="," close=")">#{id}</foreach>
+			</select>
+		</mapper>
+
+	#{name} 绑定为参数，${name} 原样替换，仅允许字段名，用于表名、排序等；
+	支持 <if test="">、<where>、<set>、<foreach collection item index open separator close>
+*/
+func LoadStatements(fsys fs.FS) error {
+	loaded := make(map[string]*statement)
+	err := fs.WalkDir(fsys, ".", func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		ext := strings.ToLower(path.Ext(file))
+		if ext != ".sql" && ext != ".xml" {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		namespace := strings.TrimSuffix(path.Base(file), path.Ext(file))
+		var list []*statement
+		if ext == ".sql" {
+			list, err = parseSqlFile(namespace, string(data))
+		} else {
+			list, err = parseXmlFile(namespace, data)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		for _, s := range list {
+			if _, ok := loaded[s.name]; ok {
+				return fmt.Errorf("%s: duplicate statement %s", file, s.name)
+			}
+			loaded[s.name] = s
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	statementMu.Lock()
+	defer statementMu.Unlock()
+	for name, s := range loaded {
+		statements[name] = s
+	}
+	return nil
+}
+
+// LoadStatementDir 从目录加载命名语句
+func LoadStatementDir(dir string) error {
+	return LoadStatements(os.DirFS(dir))
+}
+
+/*
+Statement 使用命名语句，sql 原样执行，不再使用 Table / Find 等构造的条件
+
+	@name string；--命名空间.语句名，如 user.findActive
+	@params any；--参数，struct（按 db 标签或字段名）或 map[string]any
+
+	DB.QiaoDB().Statement("user.findActive", map[string]any{"name": "a%"}).GetList(&list)
+*/
+func (mapper *Mapper) Statement(name string, params any) *Mapper {
+	statementMu.RLock()
+	s := statements[name]
+	statementMu.RUnlock()
+	if s == nil {
+		mapper.err = fmt.Errorf("%w: %s", ErrStatementNotFound, name)
+		return mapper
+	}
+	b := &stmtBuilder{root: reflect.ValueOf(params), scope: make(map[string]any)}
+	if err := b.render(s.nodes); err != nil {
+		mapper.err = fmt.Errorf("statement %s: %w", name, err)
+		return mapper
+	}
+	mapper.statement = strings.TrimSpace(b.sql.String())
+	mapper.Complete.Args = b.args
+	mapper.compose.merged = true
+	return mapper
+}
+
+var sqlNameLine = regexp.MustCompile(`(?m)^\s*--\s*name:\s*(\S+)\s*$`)
+
+func parseSqlFile(namespace, text string) (list []*statement, err error) {
+	marks := sqlNameLine.FindAllStringSubmatchIndex(text, -1)
+	for i, m := range marks {
+		end := len(text)
+		if i+1 < len(marks) {
+			end = marks[i+1][0]
+		}
+		s := &statement{name: namespace + "." + text[m[2]:m[3]]}
+		if s.nodes, err = parseSqlTags(text[m[1]:end]); err != nil {
+			return nil, fmt.Errorf("%s: %w", s.name, err)
+		}
+		list = append(list, s)
+	}
+	return
+}
+
+var (
+	sqlTag  = regexp.MustCompile(`<(/?)(if|where|set|foreach)((?:\s+\w+\s*=\s*"[^"]*")*)\s*>`)
+	sqlAttr = regexp.MustCompile(`(\w+)\s*=\s*"([^"]*)"`)
+)
+
+// .sql 中只识别动态标签，其余文本（包括 < 比较符）原样保留
+func parseSqlTags(text string) ([]stmtNode, error) {
+	root := &blockNode{}
+	stack := []*blockNode{root}
+	pos := 0
+	for _, m := range sqlTag.FindAllStringSubmatchIndex(text, -1) {
+		top := stack[len(stack)-1]
+		if m[0] > pos {
+			top.children = append(top.children, textNode(text[pos:m[0]]))
+		}
+		pos = m[1]
+		tag := text[m[4]:m[5]]
+		if m[3] > m[2] {
+			if top.tag != tag {
+				return nil, fmt.Errorf("unexpected </%s>", tag)
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		attrs := make(map[string]string)
+		for _, a := range sqlAttr.FindAllStringSubmatch(text[m[6]:m[7]], -1) {
+			attrs[a[1]] = a[2]
+		}
+		node, err := newBlock(tag, attrs)
+		if err != nil {
+			return nil, err
+		}
+		top.children = append(top.children, node)
+		stack = append(stack, node)
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("<%s> not closed", stack[len(stack)-1].tag)
+	}
+	if pos < len(text) {
+		root.children = append(root.children, textNode(text[pos:]))
+	}
+	return root.children, nil
+}
+
+func parseXmlFile(namespace string, data []byte) (list []*statement, err error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var stack []*blockNode
+	var current *statement
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			attrs := make(map[string]string)
+			for _, a := range t.Attr {
+				attrs[a.Name.Local] = a.Value
+			}
+			switch tag := t.Name.Local; {
+			case tag == "mapper":
+				if attrs["namespace"] != "" {
+					namespace = attrs["namespace"]
+				}
+			case current == nil:
+				if attrs["id"] == "" {
+					return nil, fmt.Errorf("<%s> without id", tag)
+				}
+				current = &statement{name: namespace + "." + attrs["id"]}
+				stack = []*blockNode{{tag: tag}}
+			default:
+				node, err := newBlock(tag, attrs)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", current.name, err)
+				}
+				top := stack[len(stack)-1]
+				top.children = append(top.children, node)
+				stack = append(stack, node)
+			}
+		case xml.EndElement:
+			if current == nil {
+				continue
+			}
+			if len(stack) == 1 {
+				current.nodes = stack[0].children
+				list = append(list, current)
+				current = nil
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if current != nil {
+				top := stack[len(stack)-1]
+				top.children = append(top.children, textNode(string(t)))
+			}
+		}
+	}
+	return list, nil
+}
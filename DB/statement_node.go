@@ -0,0 +1,482 @@
+package DB
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/chris-liu-zh/qiao/tools"
+)
+
+type stmtNode interface {
+	render(b *stmtBuilder) error
+}
+
+// 命名语句的渲染状态，scope 为 foreach 的 item / index
+type stmtBuilder struct {
+	sql   strings.Builder
+	args  []any
+	root  reflect.Value
+	scope map[string]any
+}
+
+func (b *stmtBuilder) render(nodes []stmtNode) error {
+	for _, n := range nodes {
+		if err := n.render(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 渲染到单独的缓冲区，供 where / set 处理首尾关键字
+func (b *stmtBuilder) renderString(nodes []stmtNode) (string, error) {
+	outer := b.sql.String()
+	b.sql.Reset()
+	err := b.render(nodes)
+	inner := b.sql.String()
+	b.sql.Reset()
+	b.sql.WriteString(outer)
+	return inner, err
+}
+
+type textNode string
+
+var (
+	stmtParam   = regexp.MustCompile(`([#$])\{\s*([\w.]+)\s*\}`)
+	stmtIdent   = regexp.MustCompile(`^[\w.]+(\s+(?i:asc|desc))?(\s*,\s*[\w.]+(\s+(?i:asc|desc))?)*$`)
+	leadingCond = regexp.MustCompile(`^(?i:and|or)\s+`)
+)
+
+func (t textNode) render(b *stmtBuilder) error {
+	text := string(t)
+	pos := 0
+	for _, m := range stmtParam.FindAllStringSubmatchIndex(text, -1) {
+		b.sql.WriteString(text[pos:m[0]])
+		pos = m[1]
+		name := text[m[4]:m[5]]
+		v, ok := b.lookup(name)
+		if !ok {
+			return fmt.Errorf("param %s not found", name)
+		}
+		if text[m[2]] == '#' {
+			b.sql.WriteString("?")
+			b.args = append(b.args, v)
+			continue
+		}
+		s := fmt.Sprint(v)
+		if !stmtIdent.MatchString(s) {
+			return fmt.Errorf("${%s}: %q is not an identifier", name, s)
+		}
+		b.sql.WriteString(s)
+	}
+	b.sql.WriteString(text[pos:])
+	return nil
+}
+
+type blockNode struct {
+	tag      string
+	attrs    map[string]string
+	test     *stmtExpr
+	children []stmtNode
+}
+
+func newBlock(tag string, attrs map[string]string) (*blockNode, error) {
+	n := &blockNode{tag: tag, attrs: attrs}
+	switch tag {
+	case "if":
+		var err error
+		if n.test, err = parseStmtExpr(attrs["test"]); err != nil {
+			return nil, fmt.Errorf("<if test=%q>: %w", attrs["test"], err)
+		}
+	case "foreach":
+		if attrs["collection"] == "" || attrs["item"] == "" {
+			return nil, fmt.Errorf("<foreach> requires collection and item")
+		}
+	case "where", "set":
+	default:
+		return nil, fmt.Errorf("unsupported tag <%s>", tag)
+	}
+	return n, nil
+}
+
+func (n *blockNode) render(b *stmtBuilder) error {
+	switch n.tag {
+	case "if":
+		ok, err := n.test.eval(b)
+		if err != nil || !ok {
+			return err
+		}
+		return b.render(n.children)
+	case "where":
+		inner, err := b.renderString(n.children)
+		if inner = leadingCond.ReplaceAllString(strings.TrimSpace(inner), ""); err == nil && inner != "" {
+			b.sql.WriteString(" where " + inner + " ")
+		}
+		return err
+	case "set":
+		inner, err := b.renderString(n.children)
+		if inner = strings.TrimSuffix(strings.TrimSpace(inner), ","); err == nil && inner != "" {
+			b.sql.WriteString(" set " + inner + " ")
+		}
+		return err
+	case "foreach":
+		return n.renderForeach(b)
+	}
+	return b.render(n.children)
+}
+
+func (n *blockNode) renderForeach(b *stmtBuilder) error {
+	v, _ := b.lookup(n.attrs["collection"])
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return fmt.Errorf("<foreach collection=%q> is not a slice", n.attrs["collection"])
+	}
+	if rv.Len() == 0 {
+		return nil
+	}
+	item, index := n.attrs["item"], n.attrs["index"]
+	saved := make(map[string]any)
+	for _, k := range []string{item, index} {
+		if v, ok := b.scope[k]; ok && k != "" {
+			saved[k] = v
+		}
+	}
+	defer func() {
+		delete(b.scope, item)
+		delete(b.scope, index)
+		for k, v := range saved {
+			b.scope[k] = v
+		}
+	}()
+	b.sql.WriteString(n.attrs["open"])
+	for i := range rv.Len() {
+		if i > 0 {
+			b.sql.WriteString(n.attrs["separator"])
+		}
+		b.scope[item] = rv.Index(i).Interface()
+		if index != "" {
+			b.scope[index] = i
+		}
+		if err := b.render(n.children); err != nil {
+			return err
+		}
+	}
+	b.sql.WriteString(n.attrs["close"])
+	return nil
+}
+
+// 按路径 a.b.c 取参数值，先查 foreach 变量再查参数
+func (b *stmtBuilder) lookup(name string) (any, bool) {
+	keys := strings.Split(name, ".")
+	var v reflect.Value
+	if s, ok := b.scope[keys[0]]; ok {
+		v, keys = reflect.ValueOf(s), keys[1:]
+	} else {
+		v = b.root
+	}
+	for _, key := range keys {
+		var ok bool
+		if v, ok = stmtField(v, key); !ok {
+			return nil, false
+		}
+	}
+	if !v.IsValid() {
+		return nil, true
+	}
+	return v.Interface(), true
+}
+
+// map 按键取值，struct 按 db 标签列名、字段名或其下划线形式取值
+func stmtField(v reflect.Value, key string) (reflect.Value, bool) {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}, false
+		}
+		f := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+		return f, f.IsValid()
+	case reflect.Struct:
+		t := v.Type()
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			column := getColumn(strings.Split(field.Tag.Get("db"), ";"))
+			if column == key || strings.EqualFold(field.Name, key) || tools.CamelCaseToUdnderscore(field.Name) == key {
+				return v.Field(i), true
+			}
+		}
+	}
+	return reflect.Value{}, false
+}
+
+/*
+stmtExpr <if test> 条件表达式
+
+	支持 and / or / not、比较 == != > >= < <=、null / true / false、数字与引号字符串；
+	单独的参数按非空判断：nil、零值、空字符串、空切片为 false
+*/
+type stmtExpr struct {
+	op          string // and / or / not / 比较符，为空时为操作数
+	left, right *stmtExpr
+	path        string
+	literal     any
+	isLiteral   bool
+}
+
+func parseStmtExpr(s string) (*stmtExpr, error) {
+	p := &exprParser{tokens: tokenizeExpr(s)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+	e, err := p.or()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return e, err
+}
+
+func tokenizeExpr(s string) (tokens []string) {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"':
+			// 含结束引号，未闭合时取到末尾
+			j := strings.IndexByte(s[i+1:], c) + i + 2
+			if j < i+2 {
+				j = len(s)
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		case strings.ContainsRune("=!<>", rune(c)):
+			if i+1 < len(s) && s[i+1] == '=' {
+				tokens = append(tokens, s[i:i+2])
+				i += 2
+			} else {
+				tokens = append(tokens, s[i:i+1])
+				i++
+			}
+		case c == '(' || c == ')':
+			tokens = append(tokens, s[i:i+1])
+			i++
+		default:
+			j := i
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '_' || s[j] == '.' || s[j] == '-') {
+				j++
+			}
+			if j == i {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	return
+}
+
+type exprParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) or() (*stmtExpr, error) {
+	left, err := p.and()
+	for err == nil && strings.EqualFold(p.peek(), "or") {
+		p.pos++
+		var right *stmtExpr
+		if right, err = p.and(); err == nil {
+			left = &stmtExpr{op: "or", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) and() (*stmtExpr, error) {
+	left, err := p.not()
+	for err == nil && strings.EqualFold(p.peek(), "and") {
+		p.pos++
+		var right *stmtExpr
+		if right, err = p.not(); err == nil {
+			left = &stmtExpr{op: "and", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) not() (*stmtExpr, error) {
+	if t := p.peek(); t == "!" || strings.EqualFold(t, "not") {
+		p.pos++
+		e, err := p.not()
+		return &stmtExpr{op: "not", left: e}, err
+	}
+	return p.compare()
+}
+
+func (p *exprParser) compare() (*stmtExpr, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	switch op := p.peek(); op {
+	case "==", "!=", ">", ">=", "<", "<=":
+		p.pos++
+		right, err := p.operand()
+		return &stmtExpr{op: op, left: left, right: right}, err
+	}
+	return left, nil
+}
+
+func (p *exprParser) operand() (*stmtExpr, error) {
+	t := p.peek()
+	if t == "" {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+	switch {
+	case t == "(":
+		e, err := p.or()
+		if err == nil && p.peek() != ")" {
+			err = fmt.Errorf("missing )")
+		}
+		p.pos++
+		return e, err
+	case t == "null" || t == "nil":
+		return &stmtExpr{isLiteral: true}, nil
+	case t == "true" || t == "false":
+		return &stmtExpr{isLiteral: true, literal: t == "true"}, nil
+	case t[0] == '\'' || t[0] == '"':
+		return &stmtExpr{isLiteral: true, literal: strings.Trim(t, `'"`)}, nil
+	}
+	if f, err := strconv.ParseFloat(t, 64); err == nil {
+		return &stmtExpr{isLiteral: true, literal: f}, nil
+	}
+	if !unicode.IsLetter(rune(t[0])) && t[0] != '_' {
+		return nil, fmt.Errorf("unexpected %q", t)
+	}
+	return &stmtExpr{path: t}, nil
+}
+
+func (e *stmtExpr) eval(b *stmtBuilder) (bool, error) {
+	switch e.op {
+	case "and":
+		ok, err := e.left.eval(b)
+		if err != nil || !ok {
+			return false, err
+		}
+		return e.right.eval(b)
+	case "or":
+		ok, err := e.left.eval(b)
+		if err != nil || ok {
+			return ok, err
+		}
+		return e.right.eval(b)
+	case "not":
+		ok, err := e.left.eval(b)
+		return !ok, err
+	case "":
+		return truthy(e.value(b)), nil
+	}
+	l, r := normalize(e.left.value(b)), normalize(e.right.value(b))
+	switch e.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	}
+	lf, lok := l.(float64)
+	rf, rok := r.(float64)
+	if !lok || !rok {
+		if l == nil || r == nil {
+			return false, nil
+		}
+		// 非数字按字符串比较
+		lf, rf = float64(strings.Compare(fmt.Sprint(l), fmt.Sprint(r))), 0
+	}
+	switch e.op {
+	case ">":
+		return lf > rf, nil
+	case ">=":
+		return lf >= rf, nil
+	case "<":
+		return lf < rf, nil
+	default:
+		return lf <= rf, nil
+	}
+}
+
+func (e *stmtExpr) value(b *stmtBuilder) any {
+	if e.isLiteral {
+		return e.literal
+	}
+	v, _ := b.lookup(e.path)
+	return v
+}
+
+// 数字统一为 float64，nil 指针视为 nil
+func normalize(v any) any {
+	rv := reflect.ValueOf(v)
+	for rv.IsValid() && (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface) {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.Slice, reflect.Map:
+		if rv.IsNil() {
+			return nil
+		}
+	}
+	return rv.Interface()
+}
+
+func equal(l, r any) bool {
+	if l == nil || r == nil {
+		return l == nil && r == nil
+	}
+	if lf, ok := l.(float64); ok {
+		rf, ok := r.(float64)
+		return ok && lf == rf
+	}
+	return fmt.Sprint(l) == fmt.Sprint(r)
+}
+
+func truthy(v any) bool {
+	rv := reflect.ValueOf(normalize(v))
+	if !rv.IsValid() {
+		return false
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return rv.Len() > 0
+	}
+	return !rv.IsZero()
+}
@@ -0,0 +1,196 @@
+package DB
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// StmtStats 预编译语句缓存统计
+type StmtStats struct {
+	Size   int    `json:"Size"`
+	Hits   uint64 `json:"Hits"`
+	Misses uint64 `json:"Misses"`
+}
+
+type stmtCache struct {
+	mu     sync.Mutex
+	size   int
+	ll     *list.List
+	items  map[string]*list.Element
+	hits   uint64
+	misses uint64
+}
+
+type stmtEntry struct {
+	query   string
+	stmt    *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+func newStmtCache(size int) *stmtCache {
+	return &stmtCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+/*
+获取预编译语句，用完后必须调用 release
+
+	@conn *sql.DB;	--数据库连接
+	@query string;	--最终sql语句
+*/
+func (c *stmtCache) get(conn *sql.DB, query string) (stmt *sql.Stmt, release func(), err error) {
+	c.mu.Lock()
+	if el, ok := c.items[query]; ok {
+		c.hits++
+		c.ll.MoveToFront(el)
+		entry := el.Value.(*stmtEntry)
+		entry.refs++
+		c.mu.Unlock()
+		return entry.stmt, c.releaseFunc(entry), nil
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	if stmt, err = conn.Prepare(query); err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[query]; ok {
+		// 并发下其他协程已缓存同一语句
+		stmt.Close()
+		entry := el.Value.(*stmtEntry)
+		entry.refs++
+		return entry.stmt, c.releaseFunc(entry), nil
+	}
+	entry := &stmtEntry{query: query, stmt: stmt, refs: 1}
+	c.items[query] = c.ll.PushFront(entry)
+	for c.ll.Len() > c.size {
+		c.remove(c.ll.Back())
+	}
+	return stmt, c.releaseFunc(entry), nil
+}
+
+func (c *stmtCache) releaseFunc(entry *stmtEntry) func() {
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		entry.refs--
+		if entry.evicted && entry.refs == 0 {
+			entry.stmt.Close()
+		}
+	}
+}
+
+// 移出缓存，仍在使用中的语句待释放后关闭
+func (c *stmtCache) remove(el *list.Element) {
+	entry := c.ll.Remove(el).(*stmtEntry)
+	delete(c.items, entry.query)
+	entry.evicted = true
+	if entry.refs == 0 {
+		entry.stmt.Close()
+	}
+}
+
+// 清空缓存
+func (c *stmtCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.ll.Len() > 0 {
+		c.remove(c.ll.Back())
+	}
+}
+
+func (c *stmtCache) stats() StmtStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return StmtStats{Size: c.ll.Len(), Hits: c.hits, Misses: c.misses}
+}
+
+// StmtStats 获取预编译语句缓存统计，未开启缓存时返回零值
+func (db *ConnDB) StmtStats() StmtStats {
+	if db == nil || db.stmts == nil {
+		return StmtStats{}
+	}
+	return db.stmts.stats()
+}
+
+func (db *ConnDB) purgeStmt() {
+	if db.stmts != nil {
+		db.stmts.purge()
+	}
+}
+
+func (db *ConnDB) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	var op *Operation
+	err := db.retry(ctx, query, args, func() error {
+		op = db.operation("query", query, args)
+		return intercept(ctx, op, db.rawQuery)
+	})
+	return op.Rows, err
+}
+
+func (db *ConnDB) rawQuery(ctx context.Context, op *Operation) (err error) {
+	if db.stmts == nil {
+		op.Rows, err = db.DBFunc.Conn.QueryContext(ctx, op.Sql, op.Args...)
+		return
+	}
+	stmt, release, err := db.stmts.get(db.DBFunc.Conn, op.Sql)
+	if err != nil {
+		return
+	}
+	defer release()
+	op.Rows, err = stmt.QueryContext(ctx, op.Args...)
+	return
+}
+
+func (db *ConnDB) queryRowScan(ctx context.Context, query string, args []any, dest ...any) error {
+	return db.retry(ctx, query, args, func() error {
+		return intercept(ctx, db.operation("queryRow", query, args), func(ctx context.Context, op *Operation) error {
+			if db.stmts == nil {
+				return db.DBFunc.Conn.QueryRowContext(ctx, op.Sql, op.Args...).Scan(dest...)
+			}
+			stmt, release, err := db.stmts.get(db.DBFunc.Conn, op.Sql)
+			if err != nil {
+				return err
+			}
+			defer release()
+			return stmt.QueryRowContext(ctx, op.Args...).Scan(dest...)
+		})
+	})
+}
+
+func (db *ConnDB) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	var op *Operation
+	err := db.retry(ctx, query, args, func() error {
+		op = db.operation("exec", query, args)
+		return intercept(ctx, op, db.rawExec)
+	})
+	return op.Result, err
+}
+
+func (db *ConnDB) rawExec(ctx context.Context, op *Operation) (err error) {
+	if db.stmts == nil {
+		op.Result, err = db.DBFunc.Conn.ExecContext(ctx, op.Sql, op.Args...)
+		return
+	}
+	stmt, release, err := db.stmts.get(db.DBFunc.Conn, op.Sql)
+	if err != nil {
+		return
+	}
+	defer release()
+	op.Result, err = stmt.ExecContext(ctx, op.Args...)
+	return
+}
+
+// 死锁等可重试错误按 RetryPolicy 重新执行
+func (db *ConnDB) retry(ctx context.Context, query string, args []any, fn func() error) error {
+	return withRetry(ctx, db.Conf.Type, db.Conf.Title, query, args, fn)
+}
@@ -0,0 +1,154 @@
+package DB
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 子查询、集合运算、公用表表达式的片段及参数，参数在生成sql时按出现顺序合并
+type compose struct {
+	with       []string
+	recursive  bool
+	withArgs   []any
+	topArgs    []any
+	fieldArgs  []any
+	tableArgs  []any
+	setArgs    []any
+	havingArgs []any
+	limitArgs  []any
+	union      string
+	unionArgs  []any
+	count      string // Count 的计数表达式，在公用表表达式之内包裹查询
+	merged     bool
+}
+
+// 生成子查询sql及参数，占位符统一为 ?，执行时再按数据库类型替换
+func (mapper *Mapper) subquery() (string, []any) {
+	sql, err := mapper.getSql()
+	if err != nil {
+		mapper.log("get sql error").logERROR(err)
+	}
+	mapper.Complete.Sql = strings.TrimSpace(sql)
+	return mapper.Complete.Sql, mapper.Complete.Args
+}
+
+/*
+With 公用表表达式
+
+	@name string；--表达式名称，可带列名，如 tree(id,parent_id)
+	@sub *Mapper；--表达式查询
+*/
+func (mapper *Mapper) With(name string, sub *Mapper) *Mapper {
+	s, args := sub.subquery()
+	mapper.compose.with = append(mapper.compose.with, fmt.Sprintf("%s as (%s)", name, s))
+	mapper.compose.withArgs = append(mapper.compose.withArgs, args...)
+	return mapper
+}
+
+// WithRecursive 递归公用表表达式，sub 通常为 UnionAll 组成的查询
+func (mapper *Mapper) WithRecursive(name string, sub *Mapper) *Mapper {
+	mapper.compose.recursive = true
+	return mapper.With(name, sub)
+}
+
+// SubField 追加子查询字段：(sub) as alias
+func (mapper *Mapper) SubField(sub *Mapper, alias string) *Mapper {
+	s, args := sub.subquery()
+	if mapper.Debris.field != "" {
+		mapper.Debris.field += ","
+	}
+	mapper.Debris.field += fmt.Sprintf("(%s) as %s", s, alias)
+	mapper.compose.fieldArgs = append(mapper.compose.fieldArgs, args...)
+	return mapper
+}
+
+// FromSub 以子查询作为查询表：from (sub) alias
+func (mapper *Mapper) FromSub(sub *Mapper, alias string) *Mapper {
+	s, args := sub.subquery()
+	mapper.Debris.table = fmt.Sprintf("(%s) %s", s, alias)
+	mapper.compose.tableArgs = args
+	return mapper
+}
+
+/*
+Union 合并查询结果并去重
+
+	集合运算时 OrderBy / Limit 作用于合并后的结果：select * from (a union b) u order by ...，排序字段使用结果列名
+*/
+func (mapper *Mapper) Union(sub *Mapper) *Mapper {
+	return mapper.setOperation("union", sub)
+}
+
+// UnionAll 合并查询结果
+func (mapper *Mapper) UnionAll(sub *Mapper) *Mapper {
+	return mapper.setOperation("union all", sub)
+}
+
+// Intersect 查询结果交集
+func (mapper *Mapper) Intersect(sub *Mapper) *Mapper {
+	return mapper.setOperation("intersect", sub)
+}
+
+// Except 查询结果差集
+func (mapper *Mapper) Except(sub *Mapper) *Mapper {
+	return mapper.setOperation("except", sub)
+}
+
+func (mapper *Mapper) setOperation(op string, sub *Mapper) *Mapper {
+	s, args := sub.subquery()
+	mapper.compose.union += fmt.Sprintf(" %s %s", op, s)
+	mapper.compose.unionArgs = append(mapper.compose.unionArgs, args...)
+	return mapper
+}
+
+/*
+拼接公用表表达式与集合运算，并按 sql 中出现的顺序合并参数
+
+	@union string；--追加在 sql 之后的集合运算，为空且存在集合运算时表示已组成在 sql 的子查询中，参数排在分页参数之前
+*/
+func (mapper *Mapper) composeSql(sql, union string) string {
+	c := &mapper.compose
+	sql += union
+	sql = mapper.countSql(sql)
+	if len(c.with) > 0 {
+		with := "with "
+		// mssql 的递归 CTE 不使用 recursive 关键字
+		if db := mapper.Read(); c.recursive && (db == nil || db.Conf.Type != "mssql") {
+			with = "with recursive "
+		}
+		sql = with + strings.Join(c.with, ",") + " " + sql
+	}
+	if !c.merged {
+		order := [][]any{c.withArgs, c.topArgs, c.fieldArgs, c.tableArgs, c.setArgs, mapper.Complete.Args, c.havingArgs, c.limitArgs, c.unionArgs}
+		if union == "" {
+			order = [][]any{c.withArgs, c.topArgs, c.fieldArgs, c.tableArgs, c.setArgs, mapper.Complete.Args, c.havingArgs, c.unionArgs, c.limitArgs}
+		}
+		var args []any
+		for _, a := range order {
+			args = append(args, a...)
+		}
+		mapper.Complete.Args = args
+		c.merged = true
+	}
+	return sql
+}
+
+// Exists exists (sub)
+func Exists(sub *Mapper) Cond {
+	s, args := sub.subquery()
+	return expr{sql: fmt.Sprintf("exists (%s)", s), args: args}
+}
+
+// NotExists not exists (sub)
+func NotExists(sub *Mapper) Cond {
+	s, args := sub.subquery()
+	return expr{sql: fmt.Sprintf("not exists (%s)", s), args: args}
+}
+
+// Count 时包裹为计数查询
+func (mapper *Mapper) countSql(sql string) string {
+	if mapper.compose.count == "" {
+		return sql
+	}
+	return fmt.Sprintf("select count(%s) from(%s) a", mapper.compose.count, sql)
+}
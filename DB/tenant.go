@@ -0,0 +1,185 @@
+package DB
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/chris-liu-zh/qiao/tools"
+)
+
+var (
+	ErrNoTenant       = errors.New("tenant required")
+	ErrTenantMismatch = errors.New("tenant mismatch")
+	ErrTenantColumn   = errors.New("tenant column cannot be updated")
+)
+
+var tenantTables sync.Map
+
+type tenantKey struct{}
+
+// WithTenant 在 context 中记录当前租户
+func WithTenant(ctx context.Context, tenant any) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom 读取 context 中的租户
+func TenantFrom(ctx context.Context) (any, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	tenant := ctx.Value(tenantKey{})
+	return tenant, tenant != nil
+}
+
+/*
+Tenant 为模型开启租户隔离，应在启动时注册
+
+	@models 结构体指针或表名；--结构体以带 tenant 选项的字段为租户列，如 db:"tenant_id;tenant"，表名默认租户列为 tenant_id
+
+	查询、Update、Del 自动加入 租户列 = ? 条件，Add、BulkLoad 自动填充租户列，租户从 Mapper.Context 中读取，
+	未设置租户时返回 ErrNoTenant；Update 的 Set 不能修改租户列，返回 ErrTenantColumn；
+	Preload 的子表与多对多中间表已注册时同样加入条件；
+	仅作用于主表，Join 的表、Query / ExecSql / Statement / Call 的 sql 不处理；
+	需要跨租户访问时使用 WithoutTenant
+*/
+func Tenant(models ...any) {
+	for _, model := range models {
+		if table, column := tenantModel(model); table != "" {
+			tenantTables.Store(table, column)
+		}
+	}
+}
+
+func tenantModel(model any) (table, column string) {
+	if name, ok := model.(string); ok {
+		return name, "tenant_id"
+	}
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return "", ""
+	}
+	column = "tenant_id"
+	for i := range t.NumField() {
+		tags := strings.Split(t.Field(i).Tag.Get("db"), ";")
+		if tagOption(tags, "tenant") {
+			if column = getColumn(tags); column == "" {
+				column = tools.CamelCaseToUdnderscore(t.Field(i).Name)
+			}
+			break
+		}
+	}
+	return tools.CamelCaseToUdnderscore(t.Name()), column
+}
+
+// WithoutTenant 本次操作不做租户隔离
+func (mapper *Mapper) WithoutTenant() *Mapper {
+	mapper.withoutTenant = true
+	return mapper
+}
+
+// 主表的租户列及当前租户，scoped 为 false 时不需要隔离
+func (mapper *Mapper) tenant() (column string, tenant any, scoped bool, err error) {
+	return mapper.tenantOf(mapper.Debris.table)
+}
+
+func (mapper *Mapper) tenantOf(table string) (column string, tenant any, scoped bool, err error) {
+	if mapper.withoutTenant || strings.TrimSpace(table) == "" {
+		return
+	}
+	c, ok := tenantTables.Load(strings.Fields(table)[0])
+	if !ok {
+		return
+	}
+	if tenant, ok = TenantFrom(mapper.context()); !ok {
+		return "", nil, true, fmt.Errorf("%w: %s", ErrNoTenant, table)
+	}
+	return c.(string), tenant, true, nil
+}
+
+// 查询、更新、删除加入租户条件，只加入一次
+func (mapper *Mapper) tenantScope() error {
+	if mapper.tenantScoped || mapper.SqlTpl == Insert {
+		return nil
+	}
+	column, tenant, scoped, err := mapper.tenant()
+	if !scoped || err != nil {
+		return err
+	}
+	if mapper.SqlTpl == Update && setsColumn(mapper.Debris.set, column) {
+		return fmt.Errorf("%w: %s", ErrTenantColumn, column)
+	}
+	mapper.tenantScoped = true
+	if mapper.Debris.alias != "" {
+		column = mapper.Debris.alias + "." + column
+	} else if mapper.Debris.join != "" {
+		column = strings.Fields(mapper.Debris.table)[0] + "." + column
+	}
+	mapper.where(column+" = ?", tenant)
+	return nil
+}
+
+// 写入时填充租户列，已有非零值与当前租户不同时报错；columns 与 Complete.Args 末尾的参数一一对应
+func (mapper *Mapper) tenantInsert(columns []string) ([]string, error) {
+	column, tenant, scoped, err := mapper.tenant()
+	if !scoped || err != nil {
+		return columns, err
+	}
+	offset := len(mapper.Complete.Args) - len(columns)
+	for i, c := range columns {
+		if c != column {
+			continue
+		}
+		if v := mapper.Complete.Args[offset+i]; v != nil && !reflect.ValueOf(v).IsZero() && fmt.Sprint(v) != fmt.Sprint(tenant) {
+			return columns, fmt.Errorf("%w: %v", ErrTenantMismatch, v)
+		}
+		mapper.Complete.Args[offset+i] = tenant
+		return columns, nil
+	}
+	mapper.Complete.Args = append(mapper.Complete.Args, tenant)
+	return append(columns, column), nil
+}
+
+// set 子句是否给 column 赋值，列名可带表名或别名前缀
+func setsColumn(set, column string) bool {
+	re := regexp.MustCompile(`(?i)(^|[\s,.])` + regexp.QuoteMeta(column) + `\s*=`)
+	return re.MatchString(set)
+}
+
+// 批量导入时填充租户列，规则同 Add；行中的租户值不一致时停止读取，错误写入 err
+func (mapper *Mapper) tenantBulk(table string, columns []string, rows iter.Seq[[]any], err *error) ([]string, iter.Seq[[]any], error) {
+	column, tenant, scoped, e := mapper.tenantOf(table)
+	if !scoped || e != nil {
+		return columns, rows, e
+	}
+	index := slices.Index(columns, column)
+	if index < 0 {
+		columns = append(slices.Clip(columns), column)
+	}
+	return columns, func(yield func([]any) bool) {
+		for row := range rows {
+			if index < 0 {
+				row = append(slices.Clip(row), tenant)
+			} else if index < len(row) {
+				if v := row[index]; v != nil && !reflect.ValueOf(v).IsZero() && fmt.Sprint(v) != fmt.Sprint(tenant) {
+					*err = fmt.Errorf("%w: %v", ErrTenantMismatch, v)
+					return
+				}
+				row = slices.Clone(row)
+				row[index] = tenant
+			}
+			if !yield(row) {
+				return
+			}
+		}
+	}, nil
+}
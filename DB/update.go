@@ -11,7 +11,7 @@ import (
 	"database/sql"
 )
 
-var Update = "Update ${table} set ${set} ${where} ${order} ${group}"
+var Update = "Update ${table} set ${set} ${where} ${group}${having} ${order}"
 
 // 更新数据并返回应向行数
 func (mapper *Mapper) UpdateAffected(set any, args ...any) (affected int64, err error) {
@@ -22,7 +22,14 @@ func (mapper *Mapper) UpdateAffected(set any, args ...any) (affected int64, err
 		return
 	}
 	mapper.debug("UpdateAffected")
-	if affected, err = mapper.Write().Affected(mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
+	if a, ok := mapper.auditor(); ok {
+		var r sql.Result
+		if r, err = mapper.auditExec(a, "update"); err != nil {
+			return
+		}
+		return r.RowsAffected()
+	}
+	if affected, err = mapper.Write().AffectedContext(mapper.context(), mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
 		return
 	}
 	return
@@ -38,7 +45,10 @@ func (mapper *Mapper) Update(data, params any, args ...any) (r sql.Result, err e
 		return
 	}
 	mapper.debug("Update")
-	if r, err = mapper.Write().Exec(mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
+	if a, ok := mapper.auditor(); ok {
+		return mapper.auditExec(a, "update")
+	}
+	if r, err = mapper.Write().ExecContext(mapper.context(), mapper.Complete.Sql, mapper.Complete.Args...); err != nil {
 		return
 	}
 	return
@@ -10,6 +10,10 @@ import (
 )
 
 func (mapper *Mapper) Find(params any, args ...any) *Mapper {
+	if cond, ok := params.(Cond); ok {
+		return mapper.whereCond(cond)
+	}
+
 	v := reflect.ValueOf(params)
 
 	if v.Kind() == reflect.String {
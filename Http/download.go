@@ -0,0 +1,79 @@
+package Http
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+const (
+	flushBytes    = 32 << 10        //累计写出超过该字节数时刷新
+	flushInterval = 1 * time.Second //距上次刷新超过该时间时刷新
+)
+
+// 按字节数或时间间隔刷新，避免每次小写入都刷新
+type flushWriter struct {
+	w         http.ResponseWriter
+	rc        *http.ResponseController
+	written   int64
+	pending   int
+	lastFlush time.Time
+}
+
+func (fw *flushWriter) Write(b []byte) (int, error) {
+	n, err := fw.w.Write(b)
+	fw.written += int64(n)
+	fw.pending += n
+	if err != nil {
+		return n, err
+	}
+	if fw.pending >= flushBytes || time.Since(fw.lastFlush) >= flushInterval {
+		fw.flush()
+	}
+	return n, nil
+}
+
+func (fw *flushWriter) flush() {
+	if fw.pending > 0 {
+		fw.rc.Flush()
+	}
+	fw.pending, fw.lastFlush = 0, time.Now()
+}
+
+/*
+Attachment 以附件形式流式下载，边生成边写出
+
+	@filename string；--下载文件名，Content-Type 按扩展名推断
+	@write func；--向响应写入内容，如 DB.QiaoDB().Export(&User{}).CSV
+*/
+func Attachment(w http.ResponseWriter, filename string, write func(io.Writer) error) error {
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	switch filepath.Ext(filename) {
+	case ".csv":
+		contentType = "text/csv;charset=UTF-8"
+	case ".jsonl":
+		contentType = "application/jsonl;charset=UTF-8"
+	case ".xlsx":
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	fw := &flushWriter{w: w, rc: http.NewResponseController(w), lastFlush: time.Now()}
+	if err := write(fw); err != nil {
+		// 尚未写出内容时仍可返回错误响应
+		if fw.written == 0 {
+			w.Header().Del("Content-Disposition")
+			Error(w, http.StatusInternalServerError, "export failed", SetWriteHeader(true), SetDebug(err))
+		}
+		return err
+	}
+	fw.flush()
+	return nil
+}
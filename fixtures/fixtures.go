@@ -0,0 +1,353 @@
+/*
+Package fixtures 测试夹具：从 YAML / JSON 文件写入表数据，清空表并重置自增，与黄金文件比对表内容
+
+夹具文件以表名为键，值为行列表，按文件中的顺序写入：
+
+	item:
+	  - {id: 1, name: a}
+	  - {id: 2, name: b}
+*/
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chris-liu-zh/qiao/DB"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrNoConn = errors.New("fixtures: no database connection")
+	ErrFormat = errors.New("fixtures: file must map table names to row lists")
+	ErrGolden = errors.New("fixtures: table content differs from golden file")
+)
+
+// Update 为 true 时 Golden 以当前表内容覆盖黄金文件，可设置环境变量 UPDATE_GOLDEN=1
+var Update = os.Getenv("UPDATE_GOLDEN") != ""
+
+type Loader struct {
+	db       *DB.ConnDB
+	identity string
+}
+
+// New 创建夹具加载器，db 通常为 DB.GetMaster() 或 DB.GetAlone()
+func New(db *DB.ConnDB) *Loader {
+	return &Loader{db: db, identity: "id"}
+}
+
+// Identity 设置自增列名，默认 id，用于写入后同步 pgsql 序列
+func (l *Loader) Identity(column string) *Loader {
+	l.identity = column
+	return l
+}
+
+type table struct {
+	name string
+	rows []map[string]any
+}
+
+/*
+Load 读取夹具文件并在一个事务中写入
+
+	@files string；--.yml / .yaml / .json 文件，支持 glob
+*/
+func (l *Loader) Load(files ...string) (err error) {
+	if l.db == nil {
+		return ErrNoConn
+	}
+	var tables []table
+	for _, pattern := range files {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("fixtures: %s: %w", pattern, os.ErrNotExist)
+		}
+		for _, file := range matches {
+			t, err := readFile(file)
+			if err != nil {
+				return fmt.Errorf("fixtures: %s: %w", file, err)
+			}
+			tables = append(tables, t...)
+		}
+	}
+	return l.tx(func(tx *DB.Begin) error {
+		for _, t := range tables {
+			if err := l.insert(tx, t); err != nil {
+				return fmt.Errorf("fixtures: %s: %w", t.name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// 解析夹具文件，JSON 作为 YAML 的子集解析，保留表的顺序
+func readFile(file string) (tables []table, err error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+	var doc yaml.Node
+	if err = yaml.Unmarshal(data, &doc); err != nil {
+		return
+	}
+	if len(doc.Content) == 0 {
+		return
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, ErrFormat
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		t := table{name: root.Content[i].Value}
+		if err = root.Content[i+1].Decode(&t.rows); err != nil {
+			return nil, ErrFormat
+		}
+		tables = append(tables, t)
+	}
+	return
+}
+
+func (l *Loader) insert(tx *DB.Begin, t table) (err error) {
+	if len(t.rows) == 0 {
+		return
+	}
+	typ := l.db.Conf.Type
+	if typ == "mssql" {
+		var identity bool
+		if err = queryRow(tx, "select cast(objectproperty(object_id(?), 'TableHasIdentity') as bit)", []any{t.name}, &identity); err != nil {
+			return
+		}
+		if identity {
+			if _, err = tx.ExecSql(fmt.Sprintf("set identity_insert %s on", t.name)); err != nil {
+				return
+			}
+			defer func() {
+				if _, offErr := tx.ExecSql(fmt.Sprintf("set identity_insert %s off", t.name)); err == nil {
+					err = offErr
+				}
+			}()
+		}
+	}
+	for _, row := range t.rows {
+		columns := make([]string, 0, len(row))
+		for c := range row {
+			columns = append(columns, c)
+		}
+		slices.Sort(columns)
+		args := make([]any, len(columns))
+		for i, c := range columns {
+			args[i] = row[c]
+		}
+		query := fmt.Sprintf("insert into %s (%s) values (%s)", t.name, strings.Join(columns, ","), DB.Placeholders(len(columns)))
+		if _, err = tx.ExecSql(query, args...); err != nil {
+			return
+		}
+	}
+	if _, ok := t.rows[0][l.identity]; ok && typ == "pgsql" {
+		// 显式写入主键后序列不会前进，同步到当前最大值
+		_, err = tx.ExecSql(fmt.Sprintf("select setval(pg_get_serial_sequence(?, ?), coalesce(max(%s), 0) + 1, false) from %s", l.identity, t.name), t.name, l.identity)
+	}
+	return
+}
+
+/*
+Truncate 清空表并重置自增计数
+
+	pgsql: truncate ... restart identity cascade
+	mysql: 关闭外键检查后 truncate，外键检查在同一连接上恢复，出错时同样恢复
+	mssql: delete 后 dbcc checkident reseed
+	sqlite: delete 后清除 sqlite_sequence
+*/
+func (l *Loader) Truncate(tables ...string) error {
+	if l.db == nil {
+		return ErrNoConn
+	}
+	return l.tx(func(tx *DB.Begin) (err error) {
+		var stmts []string
+		switch l.db.Conf.Type {
+		case "pgsql":
+			stmts = []string{fmt.Sprintf("truncate table %s restart identity cascade", strings.Join(tables, ","))}
+		case "mysql":
+			// 事务固定在一个连接上，会话变量在连接放回连接池前恢复
+			if _, err = tx.ExecSql("set foreign_key_checks = 0"); err != nil {
+				return
+			}
+			defer func() {
+				if _, resetErr := tx.ExecSql("set foreign_key_checks = 1"); err == nil {
+					err = resetErr
+				}
+			}()
+			for _, t := range tables {
+				stmts = append(stmts, "truncate table "+t)
+			}
+		case "mssql":
+			for _, t := range tables {
+				stmts = append(stmts, "delete from "+t, fmt.Sprintf("if exists (select 1 from sys.identity_columns where object_id = object_id('%[1]s') and last_value is not null) dbcc checkident('%[1]s', reseed, 0)", t))
+			}
+		default:
+			var seq int
+			if err = queryRow(tx, "select count(*) from sqlite_master where type = 'table' and name = 'sqlite_sequence'", nil, &seq); err != nil {
+				return
+			}
+			for _, t := range tables {
+				stmts = append(stmts, "delete from "+t)
+				if seq > 0 {
+					stmts = append(stmts, fmt.Sprintf("delete from sqlite_sequence where name = '%s'", t))
+				}
+			}
+		}
+		for _, s := range stmts {
+			if _, err = tx.ExecSql(s); err != nil {
+				return fmt.Errorf("fixtures: %s: %w", s, err)
+			}
+		}
+		return
+	})
+}
+
+// Reset 清空夹具文件涉及的表后重新写入
+func (l *Loader) Reset(files ...string) error {
+	var names []string
+	for _, pattern := range files {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("fixtures: %s: %w", pattern, err)
+		}
+		for _, file := range matches {
+			tables, err := readFile(file)
+			if err != nil {
+				return fmt.Errorf("fixtures: %s: %w", file, err)
+			}
+			for _, t := range tables {
+				if !slices.Contains(names, t.name) {
+					names = append(names, t.name)
+				}
+			}
+		}
+	}
+	if len(names) > 0 {
+		if err := l.Truncate(names...); err != nil {
+			return err
+		}
+	}
+	return l.Load(files...)
+}
+
+/*
+Dump 读取表内容，[]byte 转为字符串，时间格式化为 RFC3339
+
+	@orderBy string；--排序字段，保证结果稳定
+*/
+func (l *Loader) Dump(table, orderBy string) (list []map[string]any, err error) {
+	if l.db == nil {
+		return nil, ErrNoConn
+	}
+	query := "select * from " + table
+	if orderBy != "" {
+		query += " order by " + orderBy
+	}
+	rows, err := l.db.Query(query)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return
+	}
+	list = []map[string]any{}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointer := make([]any, len(columns))
+		for i := range values {
+			pointer[i] = &values[i]
+		}
+		if err = rows.Scan(pointer...); err != nil {
+			return
+		}
+		row := make(map[string]any, len(columns))
+		for i, c := range columns {
+			switch v := values[i].(type) {
+			case []byte:
+				row[c] = string(v)
+			case time.Time:
+				row[c] = v.Format(time.RFC3339)
+			default:
+				row[c] = v
+			}
+		}
+		list = append(list, row)
+	}
+	return list, rows.Err()
+}
+
+/*
+Golden 比对表内容与黄金文件（JSON），Update 为 true 时写入黄金文件
+
+	@file string；--黄金文件路径，不存在时视为不一致
+*/
+func (l *Loader) Golden(table, orderBy, file string) error {
+	list, err := l.Dump(table, orderBy)
+	if err != nil {
+		return err
+	}
+	got, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	got = append(got, '\n')
+	if Update {
+		if err = os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(file, got, 0o644)
+	}
+	want, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(bytes.TrimSpace(got), bytes.TrimSpace(want)) {
+		return fmt.Errorf("%w: %s\n got: %s\nwant: %s", ErrGolden, table, got, want)
+	}
+	return nil
+}
+
+func (l *Loader) tx(fn func(tx *DB.Begin) error) (err error) {
+	tx := l.db.BeginTx(context.Background())
+	if tx.Err != nil {
+		return tx.Err
+	}
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return
+	}
+	return tx.Commit()
+}
+
+// 事务中查询单行
+func queryRow(tx *DB.Begin, query string, args []any, dest ...any) (err error) {
+	rows, err := tx.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err == nil {
+			err = sql.ErrNoRows
+		}
+		return
+	}
+	return rows.Scan(dest...)
+}
@@ -0,0 +1,227 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+/*
+Dispatcher 轮询 outbox 表并投递待发送事件
+
+	每批在一个短事务中领取：pgsql / mysql 8.0+ 使用 for update skip locked，mssql 使用 with (updlock, readpast, rowlock)，
+	领取后将 next_at 推迟 Lease 作为租约并提交，发布在事务外进行，完成后逐条更新状态；
+	多个实例并发轮询时互不阻塞、不重复领取，进程在租约内退出时事件在租约到期后重新投递；sqlite 不支持行锁，应只运行一个实例
+
+	投递失败按 Backoff 指数退避，达到 MaxAttempts 后标记为死信并交给 DeadLetter，可通过 Outbox.Requeue 重新投递
+*/
+type Dispatcher struct {
+	outbox      *Outbox
+	publisher   Publisher
+	DeadLetter  Publisher     // 死信投递，为空时只标记状态
+	Interval    time.Duration // 轮询间隔，默认 1s，一批取满时立即继续
+	BatchSize   int           // 每批事件数，默认 100
+	MaxAttempts int           // 最大投递次数，默认 10
+	Backoff     time.Duration // 首次重试间隔，之后每次翻倍，默认 1s
+	MaxBackoff  time.Duration // 重试间隔上限，默认 5m
+	Lease       time.Duration // 领取后的租约，应大于一批的发布时间，默认 5m
+	mu          sync.Mutex
+	cancel      context.CancelFunc
+	done        chan struct{}
+}
+
+// Dispatcher 创建投递器，publisher 为事件的发布方式
+func (o *Outbox) Dispatcher(publisher Publisher) *Dispatcher {
+	return &Dispatcher{
+		outbox:      o,
+		publisher:   publisher,
+		Interval:    time.Second,
+		BatchSize:   100,
+		MaxAttempts: 10,
+		Backoff:     time.Second,
+		MaxBackoff:  5 * time.Minute,
+		Lease:       5 * time.Minute,
+	}
+}
+
+// Start 启动后台轮询，重复调用无效；ctx 取消或调用 Stop 后退出
+func (d *Dispatcher) Start(ctx context.Context) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cancel != nil {
+		return
+	}
+	ctx, d.cancel = context.WithCancel(ctx)
+	d.done = make(chan struct{})
+	go d.run(ctx, d.done)
+}
+
+// Stop 停止轮询并等待当前批次完成
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	cancel, done := d.cancel, d.done
+	d.cancel, d.done = nil, nil
+	d.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+func (d *Dispatcher) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		n, err := d.DispatchOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			title := ""
+			if db := d.outbox.db(); db != nil {
+				title = db.Conf.Title
+			}
+			DB.LogError(title, "outbox 投递失败", err, "table", d.outbox.table)
+		}
+		wait := d.Interval
+		if err == nil && n >= d.BatchSize {
+			wait = 0
+		}
+		timer.Reset(wait)
+	}
+}
+
+/*
+DispatchOnce 领取并投递一批到期事件，返回领取的事件数
+
+	发布失败不作为错误返回，只记录到事件的 last_error；ctx 取消时未发布的事件释放租约
+*/
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (n int, err error) {
+	db := d.outbox.db()
+	if db == nil {
+		return 0, ErrNoConn
+	}
+	// 停止时已发布的事件仍需写回状态，数据库操作不随 ctx 取消
+	dbCtx := context.WithoutCancel(ctx)
+	events, err := d.claim(dbCtx, db)
+	if err != nil {
+		return 0, fmt.Errorf("outbox: %w", err)
+	}
+	for i, e := range events {
+		if ctx.Err() != nil {
+			return len(events), d.release(dbCtx, db, events[i:])
+		}
+		if err = d.deliver(ctx, dbCtx, db, e); err != nil {
+			return len(events), fmt.Errorf("outbox: %w", err)
+		}
+	}
+	return len(events), nil
+}
+
+// 在短事务中锁定一批到期的待投递事件，并推迟 next_at 作为租约
+func (d *Dispatcher) claim(ctx context.Context, db *DB.ConnDB) (events []Event, err error) {
+	o := d.outbox
+	columns := "id,topic,event_key,payload,attempts,created_at"
+	var query string
+	switch db.Conf.Type {
+	case "mssql":
+		query = fmt.Sprintf("select top %d %s from %s with (updlock, readpast, rowlock) where status = ? and next_at <= ? order by id", d.BatchSize, columns, o.table)
+	case "pgsql", "mysql":
+		query = fmt.Sprintf("select %s from %s where status = ? and next_at <= ? order by id limit %d for update skip locked", columns, o.table, d.BatchSize)
+	default:
+		query = fmt.Sprintf("select %s from %s where status = ? and next_at <= ? order by id limit %d", columns, o.table, d.BatchSize)
+	}
+	now := time.Now()
+	err = o.tx(ctx, db, func(tx *DB.Begin) error {
+		if events, err = scanEvents(tx.QueryContext(ctx, query, StatusPending, now.UnixMilli())); err != nil || len(events) == 0 {
+			return err
+		}
+		args := []any{now.Add(d.Lease).UnixMilli()}
+		for _, e := range events {
+			args = append(args, e.ID)
+		}
+		_, err := tx.ExecContext(ctx, fmt.Sprintf("update %s set next_at = ? where id in (%s)", o.table, DB.Placeholders(len(events))), args...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+func scanEvents(rows *sql.Rows, err error) (events []Event, _ error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e Event
+		var created int64
+		if err = rows.Scan(&e.ID, &e.Topic, &e.Key, &e.Payload, &e.Attempts, &created); err != nil {
+			return nil, err
+		}
+		e.CreatedAt = time.UnixMilli(created)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// 发布事件并更新状态，失败时退避重试或转为死信
+func (d *Dispatcher) deliver(ctx, dbCtx context.Context, db *DB.ConnDB, e Event) error {
+	o := d.outbox
+	pubErr := d.publisher.Publish(ctx, e)
+	if pubErr == nil {
+		return o.update(dbCtx, db, "status = ?, last_error = null", e.ID, StatusSent)
+	}
+	if ctx.Err() != nil {
+		// 停止时中断的投递不计入失败次数
+		return d.release(dbCtx, db, []Event{e})
+	}
+	e.Attempts++
+	if e.Attempts < d.MaxAttempts {
+		next := time.Now().Add(d.backoff(e.Attempts)).UnixMilli()
+		return o.update(dbCtx, db, "attempts = ?, next_at = ?, last_error = ?", e.ID, e.Attempts, next, pubErr.Error())
+	}
+	DB.LogWarning(db.Conf.Title, "outbox 事件转为死信", "table", o.table, "id", e.ID, "topic", e.Topic, "error", pubErr.Error())
+	if d.DeadLetter != nil {
+		if err := d.DeadLetter.Publish(ctx, e); err != nil {
+			DB.LogError(db.Conf.Title, "outbox 死信投递失败", err, "table", o.table, "id", e.ID)
+		}
+	}
+	return o.update(dbCtx, db, "status = ?, attempts = ?, last_error = ?", e.ID, StatusDead, e.Attempts, pubErr.Error())
+}
+
+// 释放未发布事件的租约，下次轮询时重新领取
+func (d *Dispatcher) release(ctx context.Context, db *DB.ConnDB, events []Event) error {
+	args := []any{time.Now().UnixMilli(), StatusPending}
+	for _, e := range events {
+		args = append(args, e.ID)
+	}
+	_, err := db.AffectedContext(ctx, fmt.Sprintf("update %s set next_at = ? where status = ? and id in (%s)", d.outbox.table, DB.Placeholders(len(events))), args...)
+	return err
+}
+
+// 第 attempts 次失败后的等待时间
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.Backoff
+	for i := 1; i < attempts && wait < d.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if d.MaxBackoff > 0 && wait > d.MaxBackoff {
+		wait = d.MaxBackoff
+	}
+	return wait
+}
+
+func (o *Outbox) update(ctx context.Context, db *DB.ConnDB, set string, id int64, args ...any) error {
+	_, err := db.AffectedContext(ctx, fmt.Sprintf("update %s set %s where id = ?", o.table, set), append(args, id)...)
+	return err
+}
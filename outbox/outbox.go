@@ -0,0 +1,183 @@
+/*
+Package outbox 事务性发件箱：事件与业务数据在同一事务中写入 outbox 表，提交后由 Dispatcher 轮询投递
+
+	ob := outbox.New(DB.GetMaster)
+	err := DB.QiaoDB().Transaction(func(tx *DB.Begin) error {
+		tx.ExecSql("update stock set num = num - 1 where id = ?", 1)
+		return ob.Add(tx, outbox.Event{Topic: "stock.changed", Payload: []byte(`{"id":1}`)})
+	})
+
+	d := ob.Dispatcher(outbox.RedisStream(cache.Client, ""))
+	d.Start(ctx)
+	defer d.Stop()
+
+投递为至少一次，消费方应按 Event.ID 去重
+*/
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+var ErrNoConn = errors.New("outbox: no database connection")
+
+// 事件状态
+const (
+	StatusPending = 0
+	StatusSent    = 1
+	StatusDead    = 2
+)
+
+type Event struct {
+	ID        int64
+	Topic     string
+	Key       string // 业务键，如订单号，可为空
+	Payload   []byte
+	Attempts  int // 已失败的投递次数
+	CreatedAt time.Time
+}
+
+/*
+NewEvent 创建事件，payload 为 []byte / string 时原样写入，其他类型编码为 JSON
+*/
+func NewEvent(topic, key string, payload any) (Event, error) {
+	e := Event{Topic: topic, Key: key}
+	switch v := payload.(type) {
+	case []byte:
+		e.Payload = v
+	case string:
+		e.Payload = []byte(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return e, fmt.Errorf("outbox: %s: %w", topic, err)
+		}
+		e.Payload = data
+	}
+	return e, nil
+}
+
+type Outbox struct {
+	conn  func() *DB.ConnDB
+	table string
+}
+
+// New 创建发件箱，conn 返回写入业务数据的连接，通常为 DB.GetMaster，每次操作时获取，Reload 后使用新连接
+func New(conn func() *DB.ConnDB) *Outbox {
+	return &Outbox{conn: conn, table: "outbox"}
+}
+
+func (o *Outbox) db() *DB.ConnDB {
+	if o.conn == nil {
+		return nil
+	}
+	return o.conn()
+}
+
+// Table 设置表名，默认 outbox
+func (o *Outbox) Table(name string) *Outbox {
+	o.table = name
+	return o
+}
+
+/*
+Add 在事务中写入事件，与业务数据一同提交或回滚
+
+	失败时同时设置 tx.Err，Commit 时返回该错误
+*/
+func (o *Outbox) Add(tx *DB.Begin, events ...Event) error {
+	if tx.Err != nil {
+		return tx.Err
+	}
+	query := fmt.Sprintf("insert into %s (topic,event_key,payload,status,attempts,next_at,created_at) values (?,?,?,?,?,?,?)", o.table)
+	now := time.Now().UnixMilli()
+	for _, e := range events {
+		if _, err := tx.ExecSql(query, e.Topic, e.Key, e.Payload, StatusPending, 0, now, now); err != nil {
+			tx.Err = fmt.Errorf("outbox: %s: %w", e.Topic, err)
+			return tx.Err
+		}
+	}
+	return nil
+}
+
+/*
+CreateTable 按数据库类型创建 outbox 表及待投递索引，表已存在时不处理
+
+	时间列为毫秒时间戳，便于各数据库统一比较
+*/
+func (o *Outbox) CreateTable() error {
+	db := o.db()
+	if db == nil {
+		return ErrNoConn
+	}
+	t := o.table
+	var stmts []string
+	switch db.Conf.Type {
+	case "pgsql":
+		stmts = []string{
+			fmt.Sprintf("create table if not exists %s (id bigserial primary key, topic varchar(255) not null, event_key varchar(255) not null default '', payload bytea, status smallint not null default 0, attempts int not null default 0, next_at bigint not null, last_error text, created_at bigint not null)", t),
+			fmt.Sprintf("create index if not exists idx_%[1]s_pending on %[1]s (status, next_at)", t),
+		}
+	case "mysql":
+		stmts = []string{
+			fmt.Sprintf("create table if not exists %[1]s (id bigint auto_increment primary key, topic varchar(255) not null, event_key varchar(255) not null default '', payload longblob, status smallint not null default 0, attempts int not null default 0, next_at bigint not null, last_error text, created_at bigint not null, index idx_%[1]s_pending (status, next_at))", t),
+		}
+	case "mssql":
+		stmts = []string{
+			fmt.Sprintf("if object_id('%[1]s', 'U') is null begin create table %[1]s (id bigint identity(1,1) primary key, topic nvarchar(255) not null, event_key nvarchar(255) not null default '', payload varbinary(max), status smallint not null default 0, attempts int not null default 0, next_at bigint not null, last_error nvarchar(max), created_at bigint not null); create index idx_%[1]s_pending on %[1]s (status, next_at) end", t),
+		}
+	default:
+		stmts = []string{
+			fmt.Sprintf("create table if not exists %s (id integer primary key autoincrement, topic text not null, event_key text not null default '', payload blob, status integer not null default 0, attempts integer not null default 0, next_at integer not null, last_error text, created_at integer not null)", t),
+			fmt.Sprintf("create index if not exists idx_%[1]s_pending on %[1]s (status, next_at)", t),
+		}
+	}
+	for _, s := range stmts {
+		if _, err := db.Exec(s); err != nil {
+			return fmt.Errorf("outbox: %w", err)
+		}
+	}
+	return nil
+}
+
+// Purge 删除创建时间早于 olderThan 之前的已投递事件
+func (o *Outbox) Purge(olderThan time.Duration) (int64, error) {
+	db := o.db()
+	if db == nil {
+		return 0, ErrNoConn
+	}
+	return db.Affected(fmt.Sprintf("delete from %s where status = ? and created_at < ?", o.table), StatusSent, time.Now().Add(-olderThan).UnixMilli())
+}
+
+// Requeue 将死信事件重新置为待投递，并清零失败次数
+func (o *Outbox) Requeue(ids ...int64) (int64, error) {
+	db := o.db()
+	if db == nil {
+		return 0, ErrNoConn
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	args := []any{StatusPending, time.Now().UnixMilli(), StatusDead}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return db.Affected(fmt.Sprintf("update %s set status = ?, attempts = 0, next_at = ?, last_error = null where status = ? and id in (%s)", o.table, DB.Placeholders(len(ids))), args...)
+}
+
+func (o *Outbox) tx(ctx context.Context, db *DB.ConnDB, fn func(tx *DB.Begin) error) (err error) {
+	tx := db.BeginTx(ctx)
+	if tx.Err != nil {
+		return tx.Err
+	}
+	if err = fn(tx); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	return tx.Commit()
+}
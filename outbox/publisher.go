@@ -0,0 +1,90 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Publisher 发布事件，返回错误时按重试策略重新投递
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// PublisherFunc 将函数作为 Publisher
+type PublisherFunc func(ctx context.Context, e Event) error
+
+func (f PublisherFunc) Publish(ctx context.Context, e Event) error {
+	return f(ctx, e)
+}
+
+type redisStream struct {
+	client redis.Cmdable
+	stream string
+}
+
+/*
+RedisStream 通过 XADD 写入 redis stream，字段为 id、topic、key、payload
+
+	@client redis.Cmdable；--如 redisCache.RedisCache.Client
+	@stream string；--stream 名，为空时使用事件的 Topic
+*/
+func RedisStream(client redis.Cmdable, stream string) Publisher {
+	return &redisStream{client: client, stream: stream}
+}
+
+func (p *redisStream) Publish(ctx context.Context, e Event) error {
+	stream := p.stream
+	if stream == "" {
+		stream = e.Topic
+	}
+	return p.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		Values: map[string]any{"id": e.ID, "topic": e.Topic, "key": e.Key, "payload": e.Payload},
+	}).Err()
+}
+
+type webhook struct {
+	url    string
+	client *http.Client
+}
+
+/*
+Webhook 以 POST 发送事件，请求体为 Payload，返回非 2xx 状态时视为失败
+
+	请求头：X-Outbox-Id、X-Outbox-Topic、X-Outbox-Key，接收方按 X-Outbox-Id 去重
+	@client *http.Client；--为空时使用 http.DefaultClient，应设置 Timeout
+*/
+func Webhook(url string, client *http.Client) Publisher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &webhook{url: url, client: client}
+}
+
+func (p *webhook) Publish(ctx context.Context, e Event) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(e.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Outbox-Id", strconv.FormatInt(e.ID, 10))
+	req.Header.Set("X-Outbox-Topic", e.Topic)
+	req.Header.Set("X-Outbox-Key", e.Key)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook %s: %s %s", p.url, resp.Status, bytes.TrimSpace(body))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
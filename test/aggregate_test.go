@@ -0,0 +1,62 @@
+package qiao
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+type SaleTotal struct {
+	Region string     `db:"region"`
+	Total  DB.Decimal `db:"sum(amount) as total"`
+	Count  int64      `db:"count(*) as cnt"`
+}
+
+func Test_Aggregate(t *testing.T) {
+	initSqlite(t, 0)
+	db := DB.GetMaster()
+	for _, s := range []string{
+		"create table sale (id integer primary key, region text, amount decimal(10,2))",
+		"insert into sale (region, amount) values ('east', '10.10'), ('east', '20.20'), ('west', '5.05'), ('north', '1')",
+	} {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	total, err := DB.Sum[DB.Decimal](DB.QiaoDB().Table("sale").Find("region = ?", "east"), "amount")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if total != "30.3" {
+		t.Fatalf("sum: %s", total)
+	}
+	if avg, err := DB.Avg[DB.Decimal](DB.QiaoDB().Table("sale").Find("region = ?", "east"), "amount"); err != nil || avg != "15.15" {
+		t.Fatalf("avg: %s %v", avg, err)
+	}
+	maxId, err := DB.Max[int64](DB.QiaoDB().Table("sale"), "id")
+	if err != nil || maxId != 4 {
+		t.Fatalf("max: %d %v", maxId, err)
+	}
+	if empty, err := DB.Sum[float64](DB.QiaoDB().Table("sale").Find(DB.Eq("region", "none")), "amount"); err != nil || empty != 0 {
+		t.Fatalf("empty sum: %v %v", empty, err)
+	}
+
+	var totals []SaleTotal
+	mapper := DB.QiaoDB().Table("sale").Having(DB.Gt("count(*)", 0)).Find(DB.Ne("region", "north")).OrderBy("region desc").Limit(10)
+	if err = mapper.GroupList(&totals, "region"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(totals) != 2 || totals[0].Region != "west" || totals[1].Count != 2 {
+		t.Fatalf("totals: %+v", totals)
+	}
+	want := "select region,sum(amount) as total,count(*) as cnt from sale where (region <> ?) group by region having (count(*) > ?) order by region desc LIMIT ?"
+	if got := strings.Join(strings.Fields(mapper.Complete.Sql), " "); got != want {
+		t.Fatalf("sql\n got: %s\nwant: %s", got, want)
+	}
+	if !reflect.DeepEqual(mapper.Complete.Args, []any{"north", 0, 10}) {
+		t.Fatalf("args: %v", mapper.Complete.Args)
+	}
+}
@@ -0,0 +1,94 @@
+package qiao
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+type Account struct {
+	Id      int64  `db:"id;Autoincrement"`
+	Name    string `db:"name"`
+	Balance int64  `db:"balance"`
+}
+
+func Test_Audit(t *testing.T) {
+	initSqlite(t, 0)
+	db := DB.GetMaster()
+	for _, s := range []string{
+		"create table account (id integer primary key, name text, balance integer)",
+		"create table audit_log (table_name text, action text, actor text, sql_text text, args text, before_data text, after_data text, created_at datetime)",
+		"insert into account (id, name, balance) values (1, 'a', 10), (2, 'b', 20)",
+	} {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	var records []*DB.AuditRecord
+	sink := DB.AuditTable("audit_log")
+	DB.Audit(func(ctx context.Context, tx *DB.Begin, rec *DB.AuditRecord) error {
+		records = append(records, rec)
+		return sink(ctx, tx, rec)
+	}, &Account{})
+	t.Cleanup(func() { DB.Audit(nil, &Account{}) })
+
+	ctx := DB.WithActor(context.Background(), "alice")
+	affected, err := DB.QiaoDB().Table("account").Context(ctx).Find(DB.Eq("name", "a")).UpdateAffected("name = ?, balance = balance + ?", "c", 5)
+	if err != nil || affected != 1 {
+		t.Fatalf("update: %d %v", affected, err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("records: %d", len(records))
+	}
+	rec := records[0]
+	if rec.Actor != "alice" || rec.Action != "update" || len(rec.Before) != 1 || len(rec.After) != 1 {
+		t.Fatalf("record: %+v", rec)
+	}
+	if rec.Before[0]["name"] != "a" || rec.After[0]["name"] != "c" || rec.After[0]["balance"] != int64(15) {
+		t.Fatalf("images: %v %v", rec.Before, rec.After)
+	}
+
+	if _, err = DB.QiaoDB().Table("account").Context(ctx).Del(DB.Eq("id", 2)); err != nil {
+		t.Fatalf("del: %v", err)
+	}
+	if rec = records[1]; rec.Action != "delete" || len(rec.Before) != 1 || rec.After != nil {
+		t.Fatalf("record: %+v", rec)
+	}
+	if n, _ := db.Count("select count(*) from audit_log where actor = ?", "alice"); n != 2 {
+		t.Fatalf("audit rows: %d", n)
+	}
+
+	// 写入审计失败时回滚
+	DB.Audit(func(context.Context, *DB.Begin, *DB.AuditRecord) error {
+		return errors.New("sink down")
+	}, "account")
+	if _, err = DB.QiaoDB().Table("account").Del(DB.Eq("id", 1)); err == nil {
+		t.Fatal("expected sink error")
+	}
+	if n, _ := db.Count("select count(*) from account"); n != 1 {
+		t.Fatalf("rollback: %d", n)
+	}
+}
+
+func Test_AuditBefore(t *testing.T) {
+	cases := map[string]string{
+		"pgsql": "select a.* from account a inner join owner o on o.id = a.owner_id where (a.id = $1) for update of a",
+		"mysql": "select a.* from account a inner join owner o on o.id = a.owner_id where (a.id = ?) for update",
+		"mssql": "select a.* from account a with (updlock, rowlock) inner join owner o on o.id = a.owner_id where (a.id = @p1)",
+	}
+	for typ, want := range cases {
+		rec := initFake(t, DB.Config{Type: typ, Role: "master"})
+		DB.Audit(func(context.Context, *DB.Begin, *DB.AuditRecord) error { return nil }, "account")
+		if _, err := DB.QiaoDB().Table("account").Alias("a").InnerJoin("owner", "o", "o.id = a.owner_id").Del(DB.Eq("a.id", 2)); err != nil {
+			t.Fatalf("%s: %v", typ, err)
+		}
+		DB.Audit(nil, "account")
+		if calls := rec.Calls(); len(calls) < 2 || strings.Join(strings.Fields(calls[1].Sql), " ") != want {
+			t.Fatalf("%s: %#v", typ, calls)
+		}
+		DB.Stop()
+	}
+}
@@ -0,0 +1,88 @@
+package qiao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+func Test_BulkLoad(t *testing.T) {
+	initSqlite(t, 0)
+	if _, err := DB.GetMaster().Exec("create table item (id integer primary key, name text)"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	rows := func(yield func([]any) bool) {
+		for i := range 1200 {
+			if !yield([]any{i, "name"}) {
+				return
+			}
+		}
+	}
+	var batches int
+	DB.Use(func(ctx context.Context, op *DB.Operation, next DB.Handler) error {
+		if op.Tx && op.Kind == "exec" {
+			batches++
+		}
+		return next(ctx, op)
+	})
+	t.Cleanup(DB.ClearInterceptors)
+	affected, err := DB.BulkLoad("item", []string{"id", "name"}, rows)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if affected != 1200 || batches != 3 {
+		t.Fatalf("affected: %d batches: %d", affected, batches)
+	}
+	count, err := DB.QiaoDB().Count(&Item{}, "")
+	if err != nil || count != 1200 {
+		t.Fatalf("count: %d %v", count, err)
+	}
+
+	if _, err = DB.BulkLoad("item", []string{"id", "name"}, func(yield func([]any) bool) {
+		yield([]any{2000})
+	}); !errors.Is(err, DB.ErrBulkRow) {
+		t.Fatalf("row length: %v", err)
+	}
+}
+
+func Test_BulkLoadMysql(t *testing.T) {
+	rec := initFake(t, DB.Config{Type: "mysql", Role: "master"})
+	var nilInt *int
+	name := "a\tb"
+	columns := []string{"c0", "c1", "c2", "c3", "c4", "c5", "c6", "c7", "c8", "c9"}
+	row := []any{nil, nilInt, &name, sql.NullString{String: "x", Valid: true}, sql.NullString{}, DB.NullOf(5), DB.Null[int]{}, (*DB.Null[int])(nil), true, time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}
+	rec.Expect("LOAD DATA").Result(0, 1)
+	affected, err := DB.BulkLoad("item", columns, func(yield func([]any) bool) { yield(row) })
+	if err != nil || affected != 1 {
+		t.Fatalf("affected: %d %v", affected, err)
+	}
+	// \N 表示 NULL，Valuer 与指针取其值，特殊字符以反斜杠转义
+	load := rec.Calls()[1]
+	if want := `\N	\N	a\tb	x	\N	5	\N	\N	1	2025-01-02 03:04:05` + "\n"; !reflect.DeepEqual(load.Args, []any{want}) {
+		t.Fatalf("data: %q", load.Args)
+	}
+	if !strings.HasPrefix(load.Sql, "LOAD DATA LOCAL INFILE 'Reader::") || !strings.HasSuffix(load.Sql, "(c0,c1,c2,c3,c4,c5,c6,c7,c8,c9)") {
+		t.Fatalf("sql: %s", load.Sql)
+	}
+
+	// 行长度不符时停止写入并回滚
+	rec.Reset()
+	_, err = DB.BulkLoad("item", []string{"id", "name"}, func(yield func([]any) bool) {
+		if yield([]any{1, "a"}) {
+			yield([]any{2})
+		}
+	})
+	if !errors.Is(err, DB.ErrBulkRow) {
+		t.Fatalf("row length: %v", err)
+	}
+	calls := rec.Calls()
+	if len(calls) != 3 || calls[1].Args[0] != "1\ta\n" || calls[2].Sql != "ROLLBACK" {
+		t.Fatalf("calls: %#v", calls)
+	}
+}
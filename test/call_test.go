@@ -0,0 +1,90 @@
+package qiao
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+	mssql "github.com/denisenkom/go-mssqldb"
+)
+
+func Test_CallMssql(t *testing.T) {
+	rec := initFake(t, DB.Config{Title: "mssql", Type: "mssql", Role: "master"})
+	var total int64
+	n := int64(5)
+	if err := DB.QiaoDB().Call("dbo.sum_item").In("@name", "a").Out("total", &total).InOut("n", &n).Exec(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	last := rec.Last()
+	if last.Sql != "dbo.sum_item" || len(last.Args) != 4 {
+		t.Fatalf("call: %s %#v", last.Sql, last.Args)
+	}
+	want := []any{
+		sql.Named("name", "a"),
+		sql.Named("total", sql.Out{Dest: &total}),
+		sql.Named("n", sql.Out{Dest: &n, In: true}),
+	}
+	if !reflect.DeepEqual(last.Args[:3], want) {
+		t.Fatalf("args: %#v", last.Args)
+	}
+	if _, ok := last.Args[3].(*mssql.ReturnStatus); !ok {
+		t.Fatalf("return status: %#v", last.Args[3])
+	}
+}
+
+func Test_CallMysql(t *testing.T) {
+	rec := initFake(t, DB.Config{Title: "mysql", Type: "mysql", Role: "master"})
+	rec.Expect("select @total").Rows([]string{"@total", "@n"}, []any{int64(12), int64(6)})
+	var total int64
+	n := int64(5)
+	if err := DB.QiaoDB().Call("sum_item").In("name", "a").Out("total", &total).InOut("n", &n).Exec(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	calls := rec.Calls()
+	want := []DB.Call{
+		{Sql: "SET @n = ?", Args: []any{int64(5)}},
+		{Sql: "CALL sum_item(?,@total,@n)", Args: []any{"a"}},
+		{Sql: "SELECT @total,@n", Args: []any{}},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls: %#v", calls)
+	}
+	if total != 12 || n != 6 {
+		t.Fatalf("out: %d %d", total, n)
+	}
+
+	// Query 方式不支持输出参数
+	if _, err := DB.QiaoDB().Call("sum_item").Out("total", &total).Query(); err != DB.ErrProcNotSupported {
+		t.Fatalf("query: %v", err)
+	}
+}
+
+func Test_CallPgsql(t *testing.T) {
+	rec := initFake(t, DB.Config{Title: "pgsql", Type: "pgsql", Role: "master"})
+	cases := []struct {
+		function bool
+		sql      string
+	}{
+		{false, "CALL sum_item($1,NULL,$2)"},
+		{true, "select * from sum_item($1,$2)"},
+	}
+	for _, c := range cases {
+		rec.Expect("sum_item").Rows([]string{"total", "n"}, []any{int64(12), int64(6)})
+		var total int64
+		n := int64(5)
+		p := DB.QiaoDB().Call("sum_item").In("name", "a").Out("total", &total).InOut("n", &n)
+		if c.function {
+			p = p.Function()
+		}
+		if err := p.Exec(); err != nil {
+			t.Fatalf("%v", err)
+		}
+		if last := rec.Last(); last.Sql != c.sql || !reflect.DeepEqual(last.Args, []any{"a", int64(5)}) {
+			t.Fatalf("call: %s %#v", last.Sql, last.Args)
+		}
+		if total != 12 || n != 6 {
+			t.Fatalf("out: %d %d", total, n)
+		}
+	}
+}
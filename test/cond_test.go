@@ -0,0 +1,40 @@
+package qiao
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+func Test_Cond(t *testing.T) {
+	cond := DB.And(
+		DB.Or(DB.Eq("a", 1), DB.Eq("b", 2)),
+		DB.In("c", []int{3, 4, 5}),
+		DB.Not(DB.Between("d", 6, 7)),
+		DB.IsNull("e"),
+	)
+	complete, err := DB.QiaoDB().Table("t").Find(cond).GetSql()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	want := "select * from t where (((a = ?) or (b = ?)) and (c in (?,?,?)) and not (d between ? and ?) and (e is null))"
+	if got := strings.TrimSpace(complete.Sql); got != want {
+		t.Fatalf("sql\n got: %s\nwant: %s", got, want)
+	}
+	if !reflect.DeepEqual(complete.Args, []any{1, 2, 3, 4, 5, 6, 7}) {
+		t.Fatalf("args: %v", complete.Args)
+	}
+
+	if s, args := DB.In("c").Build(); s != "1 = 0" || len(args) != 0 {
+		t.Fatalf("empty in: %s %v", s, args)
+	}
+	if s, _ := DB.And(DB.Or(), DB.Like("f", "x%")).Build(); s != "f like ?" {
+		t.Fatalf("empty group: %s", s)
+	}
+	// Raw 中的 or 不与外层 and 混淆
+	if s, args := DB.And(DB.Raw("a = 1 or b = 2"), DB.Eq("c", 3)).Build(); s != "(a = 1 or b = 2) and (c = ?)" || !reflect.DeepEqual(args, []any{3}) {
+		t.Fatalf("raw: %s %v", s, args)
+	}
+}
@@ -0,0 +1,96 @@
+package qiao
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+	"github.com/chris-liu-zh/qiao/tools"
+)
+
+func Test_LoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DB_HOST", "10.0.0.1")
+	t.Setenv(DB.MasterKeyEnv, "master-key")
+	// 变量值中的引号、冒号、井号与换行不会破坏配置结构
+	t.Setenv("DB_USER", "u\"s: #1\nOpen: true")
+	aesPwd, _ := tools.SealAES("secret", "master-key")
+	sm4Pwd, _ := tools.SealSM4("secret", "master-key")
+	files := map[string]string{
+		"db.json": `{"ReconnectInterval": "2s", "Databases": [
+			{"ID": 1, "Type": "pgsql", "Role": "master", "Host": "${DB_HOST}", "Port": 5432, "User": "${DB_USER}", "DBName": "${DB_NAME:-app}", "Pwd": "AES(` + aesPwd + `)"}]}`,
+		"db.yaml": `
+ReconnectInterval: 2s
+Databases:
+  - ID: 1
+    Type: pgsql
+    Role: master
+    Host: ${DB_HOST}
+    Port: 5432
+    User: ${DB_USER}
+    DBName: ${DB_NAME:-app}
+    Pwd: SM4(` + sm4Pwd + `)
+`,
+		"db.toml": `
+ReconnectInterval = "2s"
+[[Databases]]
+ID = 1
+Type = "pgsql"
+Role = "master"
+Host = "${DB_HOST}"
+Port = 5432
+User = "${DB_USER}"
+DBName = "${DB_NAME:-app}"
+Pwd = "AES(` + aesPwd + `)"
+`,
+	}
+	for name, body := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatalf("%v", err)
+		}
+		fc, err := DB.LoadConfig(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		conf := fc.Databases[0]
+		if conf.Host != "10.0.0.1" || conf.Port != 5432 || conf.Pwd != "secret" || conf.User != "u\"s: #1\nOpen: true" || conf.DBName != "app" || conf.Open {
+			t.Fatalf("%s: %+v", name, conf)
+		}
+	}
+
+	bad := filepath.Join(dir, "bad.yml")
+	os.WriteFile(bad, []byte(`
+Databases:
+  - {ID: 1, Title: main, Type: postgres, Host: h}
+  - {ID: 1, Type: sqlite, Role: leader}
+  - {ID: 2, Type: mysql, Host: "${NOT_SET_HOST}"}
+`), 0o644)
+	_, err := DB.LoadConfig(bad)
+	if err == nil || !strings.Contains(err.Error(), "NOT_SET_HOST") {
+		t.Fatalf("expected env error, got %v", err)
+	}
+	os.WriteFile(bad, []byte(`
+Databases:
+  - {ID: 1, Title: main, Type: postgres, Host: h}
+  - {ID: 1, Type: sqlite, Role: leader}
+`), 0o644)
+	_, err = DB.LoadConfig(bad)
+	for _, want := range []string{`Databases[0] (ID 1, main): unsupported Type "postgres"`, "Databases[1] (ID 1): duplicate ID 1", "Dsn is required for sqlite", `unsupported Role "leader"`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("missing %q in %v", want, err)
+		}
+	}
+
+	sqlite := filepath.Join(dir, "sqlite.yml")
+	os.WriteFile(sqlite, []byte("Databases:\n  - {ID: 1, Type: sqlite, Role: master, Open: true, Dsn: "+filepath.Join(dir, "test.db")+"}\n"), 0o644)
+	if err = DB.InitDBFile(sqlite); err != nil {
+		t.Fatalf("%v", err)
+	}
+	t.Cleanup(DB.Stop)
+	if _, err = DB.GetMaster().Exec("create table t (id integer)"); err != nil {
+		t.Fatalf("%v", err)
+	}
+}
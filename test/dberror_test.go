@@ -0,0 +1,124 @@
+package qiao
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chris-liu-zh/qiao"
+	"github.com/chris-liu-zh/qiao/DB"
+	"github.com/chris-liu-zh/qiao/fixtures"
+	mssql "github.com/denisenkom/go-mssqldb"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func Test_DBErrorSqlite(t *testing.T) {
+	conf := DB.Config{Title: "sqlite", Role: "master", Type: "sqlite", Open: true, Dsn: t.TempDir() + "/test.db?_pragma=foreign_keys(1)"}
+	if err := DB.InitDB(false, 0, 0, conf); err != nil {
+		t.Fatalf("%v", err)
+	}
+	t.Cleanup(DB.Stop)
+	db := DB.GetMaster()
+	for _, s := range []string{
+		"create table parent (id integer primary key)",
+		"create table item (id integer primary key, name text not null unique, num int constraint chk_num check (num > 0), pid int references parent(id))",
+		"insert into item (id, name, num) values (1, 'a', 1)",
+	} {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+	}
+	cases := []struct {
+		sql                       string
+		kind                      error
+		table, column, constraint string
+	}{
+		{"insert into item (id, name, num) values (2, 'a', 1)", DB.ErrDuplicateKey, "item", "name", ""},
+		{"insert into item (id, num) values (3, 1)", DB.ErrNotNull, "item", "name", ""},
+		{"insert into item (id, name, num) values (4, 'c', 0)", DB.ErrCheck, "", "", "chk_num"},
+		{"insert into item (id, name, num, pid) values (5, 'd', 1, 9)", DB.ErrForeignKey, "", "", ""},
+	}
+	for _, c := range cases {
+		_, err := db.Exec(c.sql)
+		if !errors.Is(err, c.kind) {
+			t.Fatalf("%s: %v", c.sql, err)
+		}
+		e := DB.AsDBError(err)
+		if e.Table != c.table || e.Column != c.column || e.Constraint != c.constraint || e.Dialect != "sqlite" {
+			t.Fatalf("%s: %+v", c.sql, e)
+		}
+	}
+
+	// Mapper 写入与 qiao.Err 包装后仍可判断
+	_, err := DB.QiaoDB().Table("item").Add(&struct {
+		Id   int    `db:"id"`
+		Name string `db:"name"`
+		Num  int    `db:"num"`
+	}{6, "a", 1})
+	wrapped := qiao.Err("add item", err, qiao.SetPrintLog(false))
+	if qiao.AsErr(wrapped) == nil || !errors.Is(wrapped, DB.ErrDuplicateKey) || DB.AsDBError(wrapped).Column != "name" {
+		t.Fatalf("wrapped: %v", wrapped)
+	}
+
+	// 事务、BulkLoad、审计与夹具中的语句同样归类
+	err = DB.QiaoDB().Transaction(func(tx *DB.Begin) error {
+		_, err := tx.ExecSql("insert into item (id, name, num) values (7, 'a', 1)")
+		return err
+	})
+	if !errors.Is(err, DB.ErrDuplicateKey) {
+		t.Fatalf("transaction: %v", err)
+	}
+	if _, err = DB.BulkLoad("item", []string{"id", "name", "num"}, func(yield func([]any) bool) {
+		yield([]any{8, "a", 1})
+	}); !errors.Is(err, DB.ErrDuplicateKey) {
+		t.Fatalf("bulk load: %v", err)
+	}
+	if err = fixtures.New(db).Load("testdata/fixtures/dup_item.yml"); !errors.Is(err, DB.ErrDuplicateKey) {
+		t.Fatalf("fixtures: %v", err)
+	}
+	if _, err = db.Exec("insert into item (id, name, num) values (9, 'b', 1)"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	DB.Audit(func(context.Context, *DB.Begin, *DB.AuditRecord) error { return nil }, "item")
+	t.Cleanup(func() { DB.Audit(nil, "item") })
+	if _, err = DB.QiaoDB().Table("item").Find(DB.Eq("id", 9)).UpdateAffected("name = ?", "a"); !errors.Is(err, DB.ErrDuplicateKey) {
+		t.Fatalf("audit: %v", err)
+	}
+}
+
+func Test_DBErrorDrivers(t *testing.T) {
+	cases := []struct {
+		typ                       string
+		err                       error
+		kind                      error
+		table, column, constraint string
+	}{
+		{"mysql", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a' for key 'item.uk_name'"}, DB.ErrDuplicateKey, "item", "", "uk_name"},
+		{"mysql", &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails (`db`.`item`, CONSTRAINT `fk_item_pid` FOREIGN KEY (`pid`) REFERENCES `parent` (`id`))"}, DB.ErrForeignKey, "", "pid", "fk_item_pid"},
+		{"mysql", &mysql.MySQLError{Number: 1048, Message: "Column 'name' cannot be null"}, DB.ErrNotNull, "", "name", ""},
+		{"mysql", &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}, DB.ErrTimeout, "", "", ""},
+		{"pgsql", &pq.Error{Code: "23505", Table: "item", Constraint: "item_name_key"}, DB.ErrDuplicateKey, "item", "", "item_name_key"},
+		{"pgsql", &pq.Error{Code: "23502", Table: "item", Column: "name"}, DB.ErrNotNull, "item", "name", ""},
+		{"pgsql", &pq.Error{Code: "40P01"}, DB.ErrDeadlock, "", "", ""},
+		{"mssql", mssql.Error{Number: 2627, Message: "Violation of UNIQUE KEY constraint 'UQ_item_name'. Cannot insert duplicate key in object 'dbo.item'. The duplicate key value is (a)."}, DB.ErrDuplicateKey, "dbo.item", "", "UQ_item_name"},
+		{"mssql", mssql.Error{Number: 547, Message: `The INSERT statement conflicted with the FOREIGN KEY constraint "FK_item_pid". The conflict occurred in database "d", table "dbo.parent", column 'id'.`}, DB.ErrForeignKey, "dbo.parent", "id", "FK_item_pid"},
+		{"mssql", mssql.Error{Number: 547, Message: `The INSERT statement conflicted with the CHECK constraint "CK_item_num". The conflict occurred in database "d", table "dbo.item", column 'num'.`}, DB.ErrCheck, "dbo.item", "num", "CK_item_num"},
+		{"mssql", mssql.Error{Number: 515, Message: "Cannot insert the value NULL into column 'name', table 'd.dbo.item'; column does not allow nulls. INSERT fails."}, DB.ErrNotNull, "d.dbo.item", "name", ""},
+	}
+	DB.SetRetry(DB.RetryPolicy{})
+	t.Cleanup(func() {
+		DB.SetRetry(DB.RetryPolicy{MaxAttempts: 3, Backoff: 50 * time.Millisecond, MaxBackoff: time.Second})
+	})
+	for _, c := range cases {
+		rec := initFake(t, DB.Config{Title: c.typ, Type: c.typ, Role: "master"})
+		rec.Expect("insert").Error(c.err)
+		_, err := DB.GetMaster().Exec("insert into item (name) values (?)", "a")
+		e := DB.AsDBError(err)
+		if !errors.Is(err, c.kind) || e.Code == "" || e.Table != c.table || e.Column != c.column || e.Constraint != c.constraint {
+			t.Fatalf("%s %v: %+v", c.typ, c.err, e)
+		}
+		DB.Stop()
+	}
+}
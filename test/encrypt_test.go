@@ -0,0 +1,101 @@
+package qiao
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+type Person struct {
+	Id     int64  `db:"id;Autoincrement"`
+	Name   string `db:"name"`
+	IdCard string `db:"id_card;encrypt;blind:id_card_bidx"`
+	Phone  string `db:"phone;encrypt"`
+}
+
+func Test_Encrypt(t *testing.T) {
+	initSqlite(t, 0)
+	db := DB.GetMaster()
+	if _, err := db.Exec("create table person (id integer primary key, name text, id_card text, id_card_bidx text, phone text)"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	k := DB.NewKeyring()
+	if err := k.AddAES("k1", []byte("0123456789abcdef0123456789abcdef")); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := k.AddSM4("k2", []byte("fedcba9876543210")); err != nil {
+		t.Fatalf("%v", err)
+	}
+	k.BlindKey([]byte("blind"))
+	DB.SetKeyring(k)
+	t.Cleanup(func() { DB.SetKeyring(nil) })
+
+	if _, err := DB.QiaoDB().Add(&Person{Name: "a", IdCard: "110101199001011234", Phone: "13800000000"}); err != nil {
+		t.Fatalf("%v", err)
+	}
+	// 轮换密钥后旧数据仍可解密
+	if err := k.Use("k2"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := DB.QiaoDB().Add(&Person{Name: "b", IdCard: "220101199001015678", Phone: "13900000000"}); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	var raw string
+	if rows, err := db.Query("select id_card from person where name = ?", "b"); err == nil {
+		for rows.Next() {
+			rows.Scan(&raw)
+		}
+		rows.Close()
+	}
+	if !strings.HasPrefix(raw, "k2$") || strings.Contains(raw, "5678") {
+		t.Fatalf("stored: %s", raw)
+	}
+
+	var list []Person
+	if err := DB.QiaoDB().Table("person").OrderBy("id").GetList(&list); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(list) != 2 || list[0].IdCard != "110101199001011234" || list[1].Phone != "13900000000" {
+		t.Fatalf("list: %+v", list)
+	}
+
+	var found []Person
+	if err := DB.QiaoDB().Table("person").Find(DB.BlindEq("id_card_bidx", "110101199001011234")).GetList(&found); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(found) != 1 || found[0].Name != "a" {
+		t.Fatalf("found: %+v", found)
+	}
+
+	// 非 string / []byte 的 encrypt 字段读取时返回错误，不会 panic
+	var phones []struct {
+		Phone int64 `db:"phone;encrypt"`
+	}
+	if err := DB.QiaoDB().Table("person").GetList(&phones); err == nil || !strings.Contains(err.Error(), "encrypt field must be string or []byte") {
+		t.Fatalf("expected encrypt type error, got %v", err)
+	}
+
+	// 未设置 BlindKey 时不能写入或查询盲索引
+	noBlind := DB.NewKeyring()
+	if err := noBlind.AddAES("k1", []byte("0123456789abcdef0123456789abcdef")); err != nil {
+		t.Fatalf("%v", err)
+	}
+	DB.SetKeyring(noBlind)
+	if _, err := DB.QiaoDB().Add(&Person{Name: "c", IdCard: "x"}); !errors.Is(err, DB.ErrNoBlindKey) {
+		t.Fatalf("expected ErrNoBlindKey, got %v", err)
+	}
+	if err := DB.QiaoDB().Table("person").Find(DB.BlindEq("id_card_bidx", "x")).GetList(&found); !errors.Is(err, DB.ErrNoBlindKey) {
+		t.Fatalf("expected ErrNoBlindKey, got %v", err)
+	}
+
+	DB.SetKeyring(nil)
+	if _, err := DB.QiaoDB().Add(&Person{Name: "c", IdCard: "x"}); err != DB.ErrNoKeyring {
+		t.Fatalf("expected ErrNoKeyring, got %v", err)
+	}
+	if err := DB.QiaoDB().Table("person").Find(DB.And(DB.Eq("name", "a"), DB.BlindEq("id_card_bidx", "x"))).GetList(&found); !errors.Is(err, DB.ErrNoKeyring) {
+		t.Fatalf("expected ErrNoKeyring, got %v", err)
+	}
+}
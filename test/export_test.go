@@ -0,0 +1,104 @@
+package qiao
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+	"github.com/chris-liu-zh/qiao/Http"
+)
+
+type ExportItem struct {
+	Id   int64  `db:"id" json:"编号"`
+	Name string `db:"name"`
+}
+
+func initExport(t *testing.T) {
+	initSqlite(t, 0)
+	if _, err := DB.GetMaster().Exec("create table export_item (id integer primary key, name text)"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	for i, name := range []string{"a", `b,"c"`} {
+		if _, err := DB.QiaoDB().Add(&ExportItem{Id: int64(i + 1), Name: name}); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+}
+
+func Test_Export(t *testing.T) {
+	initExport(t)
+
+	var buf bytes.Buffer
+	if err := DB.QiaoDB().OrderBy("id").Export(&ExportItem{}).BOM(true).CSV(&buf); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if want := "\xEF\xBB\xBF编号,name\n1,a\n2,\"b,\"\"c\"\"\"\n"; buf.String() != want {
+		t.Fatalf("csv: %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := DB.QiaoDB().Table("export_item").Field("id,name").OrderBy("id").Export(nil).JSONL(&buf); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if want := "{\"id\":1,\"name\":\"a\"}\n{\"id\":2,\"name\":\"b,\\\"c\\\"\"}\n"; buf.String() != want {
+		t.Fatalf("jsonl: %q", buf.String())
+	}
+
+	w := httptest.NewRecorder()
+	if err := Http.Attachment(w, "items.xlsx", DB.QiaoDB().Export(&ExportItem{}).XLSX); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename=items.xlsx` {
+		t.Fatalf("Content-Disposition: %s", cd)
+	}
+	body := w.Body.Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	for _, f := range zr.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		rc, _ := f.Open()
+		sheet, _ := io.ReadAll(rc)
+		if !strings.Contains(string(sheet), `<t xml:space="preserve">b,&#34;c&#34;</t>`) {
+			t.Fatalf("sheet: %s", sheet)
+		}
+		return
+	}
+	t.Fatal("sheet1.xml not found")
+}
+
+type flushCounter struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (f *flushCounter) Flush() {
+	f.flushes++
+	f.ResponseRecorder.Flush()
+}
+
+func Test_AttachmentFlush(t *testing.T) {
+	w := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+	err := Http.Attachment(w, "rows.csv", func(out io.Writer) error {
+		for range 1000 {
+			if _, err := io.WriteString(out, "1,a\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	// 小块写入不逐次刷新，结束时刷新一次
+	if w.flushes != 1 || w.Body.Len() != 4000 {
+		t.Fatalf("flushes: %d, body: %d", w.flushes, w.Body.Len())
+	}
+}
@@ -0,0 +1,76 @@
+package qiao
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+func initFake(t *testing.T, conf DB.Config) *DB.Recorder {
+	t.Helper()
+	rec, err := DB.NewFake(conf)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	t.Cleanup(DB.Stop)
+	return rec
+}
+
+func Test_FakeDialect(t *testing.T) {
+	cases := []struct {
+		typ  string
+		sql  string
+		args []any
+	}{
+		{"pgsql", "select id,name from item where (name = $1) order by id LIMIT $2 OFFSET $3", []any{"a", 10, 10}},
+		{"mysql", "select id,name from item where (name = ?) order by id LIMIT ?,?", []any{"a", 10, 10}},
+		{"mssql", "select top (@p1) id,name from (select row_number() over(order by id) as rownumber,id,name from item where (name = @p2) ) temp_row where rownumber > @p3 order by rownumber", []any{10, "a", 10}},
+	}
+	for _, c := range cases {
+		t.Run(c.typ, func(t *testing.T) {
+			rec := initFake(t, DB.Config{Title: c.typ, Type: c.typ, Role: "master"})
+			rec.Expect("from item").Rows([]string{"id", "name"}, []any{1, "a"}, []any{2, "a"})
+			var list []Item
+			if err := DB.QiaoDB().Table("item").Field("id,name").Find(DB.Eq("name", "a")).OrderBy("id").Limit(10, 2).GetList(&list); err != nil {
+				t.Fatalf("%v", err)
+			}
+			if len(list) != 2 || list[1].Id != 2 {
+				t.Fatalf("list: %+v", list)
+			}
+			last := rec.Last()
+			if got := strings.Join(strings.Fields(last.Sql), " "); got != c.sql {
+				t.Fatalf("sql\n got: %s\nwant: %s", got, c.sql)
+			}
+			if !reflect.DeepEqual(last.Args, c.args) {
+				t.Fatalf("args: %#v", last.Args)
+			}
+		})
+	}
+}
+
+func Test_FakeFailover(t *testing.T) {
+	// 在启动重连前设置，清理在 Stop 等待重连退出之后执行
+	DB.Pool.SwitchRole, DB.Pool.ReconnectNum = true, 0
+	t.Cleanup(func() { DB.Pool.SwitchRole = false })
+	master := initFake(t, DB.Config{Title: "master", Type: "pgsql", Role: "master"})
+	slave := initFake(t, DB.Config{Title: "slave", Type: "pgsql", Role: "slave"})
+
+	master.Expect("delete").OpError()
+	master.Down(true)
+	slave.Expect("delete").Result(0, 3)
+	affected, err := DB.QiaoDB().Table("item").DelAffected(DB.Lt("id", 10))
+	if err != nil || affected != 3 {
+		t.Fatalf("del: %d %v", affected, err)
+	}
+	if !DB.Pool.Master.DBConn[0].IsClose {
+		t.Fatal("master should be closed")
+	}
+	if got := strings.Join(strings.Fields(slave.Last().Sql), " "); got != "DELETE FROM item where (id < $1)" {
+		t.Fatalf("slave sql: %s", got)
+	}
+	if args := slave.Last().Args; !reflect.DeepEqual(args, []any{10}) {
+		t.Fatalf("slave args: %#v", args)
+	}
+}
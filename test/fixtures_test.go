@@ -0,0 +1,67 @@
+package qiao
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+	"github.com/chris-liu-zh/qiao/fixtures"
+)
+
+func Test_Fixtures(t *testing.T) {
+	initSqlite(t, 0)
+	db := DB.GetMaster()
+	for _, s := range []string{
+		"create table item (id integer primary key, name text)",
+		"create table tag (id integer primary key autoincrement, name text)",
+	} {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	f := fixtures.New(db)
+	files := []string{"testdata/fixtures/item.yml", "testdata/fixtures/tag.json"}
+	for range 2 {
+		if err := f.Reset(files...); err != nil {
+			t.Fatalf("%v", err)
+		}
+		if err := f.Golden("tag", "id", "testdata/fixtures/tag.golden.json"); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	list, err := f.Dump("item", "id")
+	if err != nil || len(list) != 2 || list[1]["name"] != "pear" {
+		t.Fatalf("dump: %v %v", list, err)
+	}
+
+	if _, err = db.Exec("update tag set name = ? where id = ?", "rotten", 2); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err = f.Golden("tag", "id", "testdata/fixtures/tag.golden.json"); !errors.Is(err, fixtures.ErrGolden) {
+		t.Fatalf("expected golden diff, got %v", err)
+	}
+}
+
+func Test_FixturesTruncateMysql(t *testing.T) {
+	rec := initFake(t, DB.Config{Title: "mysql", Type: "mysql", Role: "master"})
+	failed := errors.New("truncate failed")
+	rec.Expect("truncate table tag").Error(failed)
+	f := fixtures.New(DB.GetMaster())
+	if err := f.Truncate("item", "tag"); !errors.Is(err, failed) {
+		t.Fatalf("truncate: %v", err)
+	}
+	var got []string
+	for _, c := range rec.Calls() {
+		got = append(got, c.Sql)
+	}
+	want := []string{"BEGIN", "set foreign_key_checks = 0", "truncate table item", "truncate table tag", "set foreign_key_checks = 1", "ROLLBACK"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("calls: %q", got)
+	}
+
+	if err := f.Reset("testdata/fixtures/[.yml"); !errors.Is(err, filepath.ErrBadPattern) {
+		t.Fatalf("reset: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package qiao
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+type traceKey struct{}
+
+func Test_Interceptor(t *testing.T) {
+	rec := initFake(t, DB.Config{Title: "fake", Type: "pgsql", Role: "master"})
+	t.Cleanup(DB.ClearInterceptors)
+
+	var order []string
+	var ops []DB.Operation
+	DB.Use(func(ctx context.Context, op *DB.Operation, next DB.Handler) error {
+		order = append(order, "outer")
+		err := next(ctx, op)
+		ops = append(ops, *op)
+		return err
+	}, func(ctx context.Context, op *DB.Operation, next DB.Handler) error {
+		trace, _ := ctx.Value(traceKey{}).(string)
+		order = append(order, "inner:"+trace)
+		op.Sql = strings.Replace(op.Sql, "from item", "from item_v2", 1)
+		return next(ctx, op)
+	})
+
+	rec.Expect("from item_v2").Rows([]string{"id", "name"}, []any{1, "a"})
+	var list []Item
+	ctx := context.WithValue(context.Background(), traceKey{}, "req-1")
+	if err := DB.QiaoDB().Context(ctx).Table("item").Field("id,name").Find(DB.Eq("name", "a")).GetList(&list); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(list) != 1 || !strings.Contains(rec.Last().Sql, "from item_v2") {
+		t.Fatalf("rewrite: %+v %s", list, rec.Last().Sql)
+	}
+	if strings.Join(order, ",") != "outer,inner:req-1" {
+		t.Fatalf("order: %v", order)
+	}
+	op := ops[0]
+	if op.Kind != "query" || op.Role != "master" || op.Title != "fake" || op.Rows == nil || op.Err != nil || len(op.Args) != 1 {
+		t.Fatalf("operation: %+v", op)
+	}
+
+	// 策略拦截器拒绝执行，语句不会到达驱动
+	errDenied := errors.New("delete without where")
+	DB.Use(func(ctx context.Context, op *DB.Operation, next DB.Handler) error {
+		if strings.HasPrefix(op.Sql, "delete") && !strings.Contains(op.Sql, "where") {
+			return errDenied
+		}
+		return next(ctx, op)
+	})
+	rec.Reset()
+	if _, err := DB.GetMaster().Exec("delete from item"); !errors.Is(err, errDenied) {
+		t.Fatalf("err: %v", err)
+	}
+	if len(rec.Calls()) != 0 {
+		t.Fatalf("calls: %+v", rec.Calls())
+	}
+	rec.Expect("delete").Result(0, 3)
+	affected, err := DB.GetMaster().Affected("delete from item where id > ?", 1)
+	if err != nil || affected != 3 {
+		t.Fatalf("affected: %d %v", affected, err)
+	}
+	if last := ops[len(ops)-1]; last.Kind != "exec" || last.Result == nil {
+		t.Fatalf("exec operation: %+v", last)
+	}
+
+	// 事务中的语句同样经过拦截器链
+	rec.Expect("update stock").Result(0, 1)
+	err = DB.QiaoDB().Transaction(func(tx *DB.Begin) error {
+		_, err := tx.ExecSql("update stock set num = num - 1 where id = ?", 1)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("transaction: %v", err)
+	}
+	if last := ops[len(ops)-1]; !last.Tx || last.Sql != "update stock set num = num - 1 where id = $1" {
+		t.Fatalf("tx operation: %+v", last)
+	}
+}
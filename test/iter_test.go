@@ -0,0 +1,87 @@
+package qiao
+
+import (
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+type Item struct {
+	Id   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+func Test_Iter(t *testing.T) {
+	initSqlite(t, 0)
+	db := DB.GetMaster()
+	if _, err := db.Exec("create table item (id integer primary key, name text)"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	for i := range 5 {
+		if _, err := DB.QiaoDB().Add(&Item{Id: int64(i), Name: "name"}); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	var n int
+	for item, err := range DB.Iter[Item](DB.QiaoDB().OrderBy("id").Limit(3)) {
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if item.Id != int64(n) {
+			t.Fatalf("item: %+v", item)
+		}
+		n++
+	}
+	if n != 3 {
+		t.Fatalf("rows: %d", n)
+	}
+
+	mapper, err := DB.QiaoDB().Query("select id, name from item where id > ?", 1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	for row, err := range mapper.IterMap() {
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if row["id"] != int64(2) {
+			t.Fatalf("row: %v", row)
+		}
+		break
+	}
+
+	// 同一个 Seq 可重复遍历，Query 的结果集用完后重新执行原 sql
+	for range 2 {
+		n = 0
+		for _, err := range mapper.IterMap() {
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			n++
+		}
+		if n != 3 {
+			t.Fatalf("rows: %d", n)
+		}
+	}
+
+	// GetList 之后在同一个 Mapper 上遍历
+	list := DB.QiaoDB().Find("id < ?", 2)
+	var items []Item
+	if err = list.GetList(&items); err != nil || len(items) != 2 {
+		t.Fatalf("list: %v %v", items, err)
+	}
+	seq := DB.Iter[Item](list)
+	for range 2 {
+		n = 0
+		for _, err := range seq {
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			n++
+		}
+		if n != 2 {
+			t.Fatalf("rows: %d", n)
+		}
+	}
+}
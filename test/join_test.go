@@ -0,0 +1,101 @@
+package qiao
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+type JoinCustomer struct {
+	Id   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+type CustomerTotal struct {
+	Total int64  `db:"total"`
+	Name  string `db:"name"`
+}
+
+type JoinItem struct {
+	Title string `db:"title"`
+}
+
+type JoinOrder struct {
+	Id       int64        `db:"o.id"`
+	Customer JoinCustomer `db:"c"`
+	JoinItem `db:"i"`
+}
+
+func Test_Join(t *testing.T) {
+	initSqlite(t, 0)
+	db := DB.GetMaster()
+	for _, s := range []string{
+		"create table orders (id integer primary key, customer_id integer)",
+		"create table customer (id integer primary key, name text)",
+		"create table order_item (order_id integer, title text)",
+		"insert into customer (id, name) values (1, 'alice'), (2, 'bob')",
+		"insert into orders (id, customer_id) values (10, 1), (11, 2)",
+		"insert into order_item (order_id, title) values (10, 'book'), (11, 'pen')",
+	} {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	var orders []JoinOrder
+	err := DB.QiaoDB().Table("orders").Alias("o").
+		InnerJoin("customer", "c", "c.id = o.customer_id").
+		LeftJoin("order_item", "i", "i.order_id = o.id").
+		Find(DB.Gt("o.id", 0)).OrderBy("o.id").Limit(10).GetList(&orders)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(orders) != 2 || orders[1].Id != 11 || orders[1].Customer.Name != "bob" || orders[1].Title != "pen" {
+		t.Fatalf("orders: %+v", orders)
+	}
+
+	var order JoinOrder
+	if err = DB.QiaoDB().Table("orders").Alias("o").
+		InnerJoin("customer", "c", "c.id = o.customer_id").
+		LeftJoin("order_item", "i", "i.order_id = o.id").
+		Find("o.id = ?", 10).Get(&order); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if order.Customer.Id != 1 || order.Title != "book" {
+		t.Fatalf("order: %+v", order)
+	}
+
+	// 只查询部分字段时按列名填充，其余字段保持零值
+	var customer JoinCustomer
+	if err = DB.QiaoDB().Table("customer").Field("name").Find("id = ?", 2).Get(&customer); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if customer.Name != "bob" || customer.Id != 0 {
+		t.Fatalf("customer: %+v", customer)
+	}
+	var customers []JoinCustomer
+	if err = DB.QiaoDB().Table("customer").Field("name, id").OrderBy("id").GetList(&customers); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(customers) != 2 || customers[1].Id != 2 || customers[1].Name != "bob" {
+		t.Fatalf("customers: %+v", customers)
+	}
+
+	// 原生 Query 中名称对应不上的列按位置读入
+	mapper, err := DB.QiaoDB().Query("select count(*) as cnt, name from customer where id = ? group by name", 2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var total CustomerTotal
+	if err = mapper.ScanRowStruct(&total); err != nil || total.Total != 1 || total.Name != "bob" {
+		t.Fatalf("total: %+v %v", total, err)
+	}
+	// 位置上的字段已按名称对应时返回错误，而不是丢弃该列
+	if mapper, err = DB.QiaoDB().Query("select name, id from customer where id = ?", 2); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err = mapper.ScanRowStruct(&total); !errors.Is(err, DB.ErrUnmatchedColumn) {
+		t.Fatalf("unmatched: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package qiao
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+type Address struct {
+	City string `json:"city"`
+	Zip  string `json:"zip"`
+}
+
+type Profile struct {
+	Id      int64             `db:"id;Autoincrement"`
+	Tags    []string          `db:"tags;json"`
+	Attrs   map[string]any    `db:"attrs;json"`
+	Address Address           `db:"address;json"`
+	Extra   *map[string]int64 `db:"extra;json"`
+}
+
+func Test_JSONField(t *testing.T) {
+	initSqlite(t, 0)
+	db := DB.GetMaster()
+	if _, err := db.Exec("create table profile (id integer primary key, tags text, attrs text, address text, extra text)"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	data := []Profile{
+		{Tags: []string{"a", "b"}, Attrs: map[string]any{"age": 30}, Address: Address{City: "Hangzhou", Zip: "310000"}},
+		{Tags: []string{"c"}, Address: Address{City: "Beijing"}},
+		{Tags: []string{"e"}},
+	}
+	for i := range data {
+		if _, err := DB.QiaoDB().Add(&data[i]); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	if _, err := DB.QiaoDB().Table("profile").Find(DB.Eq("id", 2)).Update(&Profile{Tags: []string{"d"}, Attrs: map[string]any{"vip": true}, Address: Address{City: "Shanghai"}}, nil); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	var list []Profile
+	if err := DB.QiaoDB().Table("profile").OrderBy("id").GetList(&list); err != nil {
+		t.Fatalf("%v", err)
+	}
+	// 每行各自反序列化，不共用上一行的 map / slice
+	want := []Profile{
+		{Id: 1, Tags: []string{"a", "b"}, Attrs: map[string]any{"age": float64(30)}, Address: Address{City: "Hangzhou", Zip: "310000"}},
+		{Id: 2, Tags: []string{"d"}, Attrs: map[string]any{"vip": true}, Address: Address{City: "Shanghai"}},
+		{Id: 3, Tags: []string{"e"}},
+	}
+	if !reflect.DeepEqual(list, want) {
+		t.Fatalf("list: %+v", list)
+	}
+	// nil 的 map / 指针存为 NULL
+	if n, err := DB.QiaoDB().Find("attrs is null and extra is null").Count(&Profile{}, "id"); err != nil || n != 1 {
+		t.Fatalf("null: %d %v", n, err)
+	}
+
+	var found Profile
+	mapper := DB.QiaoDB().Table("profile")
+	mapper.Find(DB.And(mapper.JSONEq("address", "$.city", "Hangzhou"), DB.Eq(mapper.JSONPath("tags", "[1]"), "b")))
+	if err := mapper.Get(&found); err != nil || found.Id != 1 {
+		t.Fatalf("found: %+v %v", found, err)
+	}
+	if got := strings.Join(strings.Fields(mapper.Complete.Sql), " "); !strings.Contains(got, "(json_extract(address, '$.city') = ?) and (json_extract(tags, '$[1]') = ?)") {
+		t.Fatalf("sql: %s", got)
+	}
+}
+
+func Test_JSONPathDialect(t *testing.T) {
+	cases := map[string]string{
+		"pgsql": "attrs->'address'->'lines'->0->>'city'",
+		"mysql": "attrs->>'$.address.lines[0].city'",
+		"mssql": "JSON_VALUE(attrs, '$.address.lines[0].city')",
+	}
+	for typ, want := range cases {
+		initFake(t, DB.Config{Type: typ, Role: "master"})
+		if got := DB.QiaoDB().JSONPath("attrs", "$.address.lines[0].city"); got != want {
+			t.Fatalf("%s: got %s, want %s", typ, got, want)
+		}
+		DB.Stop()
+	}
+}
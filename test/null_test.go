@@ -0,0 +1,64 @@
+package qiao
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+type Member struct {
+	Id       int64             `db:"id;Autoincrement" json:"id"`
+	Nickname DB.Null[string]   `db:"nickname" json:"nickname"`
+	Age      DB.Null[int64]    `db:"age" json:"age"`
+	Email    *string           `db:"email" json:"email"`
+	Remark   sql.NullString    `db:"remark" json:"-"`
+	Score    *DB.Null[float64] `db:"score" json:"-"`
+}
+
+func Test_Null(t *testing.T) {
+	initSqlite(t, 0)
+	db := DB.GetMaster()
+	if _, err := db.Exec("create table member (id integer primary key, nickname text, age integer, email text, remark text, score real)"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	email := "a@b.c"
+	for _, m := range []*Member{
+		{Nickname: DB.NullOf("a"), Age: DB.NullOf[int64](18), Email: &email, Remark: sql.NullString{String: "r", Valid: true}},
+		{},
+	} {
+		if _, err := DB.QiaoDB().Add(m); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	if n, _ := db.Count("select count(*) from member where remark is null and nickname is null and age is null"); n != 1 {
+		t.Fatalf("null rows: %d", n)
+	}
+
+	var list []Member
+	if err := DB.QiaoDB().Table("member").OrderBy("id").GetList(&list); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(list) != 2 || list[0].Age.V != 18 || *list[0].Email != email || list[1].Email != nil || list[1].Nickname.Valid || list[1].Score != nil {
+		t.Fatalf("list: %+v", list)
+	}
+	b, _ := json.Marshal(list[1])
+	if string(b) != `{"id":2,"nickname":null,"age":null,"email":null}` {
+		t.Fatalf("json: %s", b)
+	}
+	var m Member
+	if err := json.Unmarshal([]byte(`{"nickname":"x","age":null}`), &m); err != nil || !m.Nickname.Valid || m.Nickname.V != "x" || m.Age.Valid {
+		t.Fatalf("unmarshal: %+v %v", m, err)
+	}
+
+	var buf bytes.Buffer
+	if err := DB.QiaoDB().Table("member").OrderBy("id").Export(&Member{}).CSV(&buf); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); lines[1] != "1,a,18,a@b.c,r," || lines[2] != "2,,,,," {
+		t.Fatalf("csv: %q", lines)
+	}
+}
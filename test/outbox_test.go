@@ -0,0 +1,150 @@
+package qiao
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chris-liu-zh/qiao/DB"
+	"github.com/chris-liu-zh/qiao/outbox"
+)
+
+func Test_Outbox(t *testing.T) {
+	initSqlite(t, 0)
+	db := DB.GetMaster()
+	ob := outbox.New(DB.GetMaster)
+	if err := ob.CreateTable(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := db.Exec("create table stock (id integer primary key, num int)"); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// 回滚的事务不产生事件
+	boom := errors.New("boom")
+	err := DB.QiaoDB().Transaction(func(tx *DB.Begin) error {
+		if _, err := tx.ExecSql("insert into stock (id, num) values (1, 10)"); err != nil {
+			return err
+		}
+		if err := ob.Add(tx, outbox.Event{Topic: "stock", Payload: []byte("rolled back")}); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("rollback: %v", err)
+	}
+	for i := range 3 {
+		err = DB.QiaoDB().Transaction(func(tx *DB.Begin) error {
+			if _, err := tx.ExecSql("insert into stock (id, num) values (?, 10)", i+1); err != nil {
+				return err
+			}
+			e, err := outbox.NewEvent("stock", "", map[string]int{"id": i + 1})
+			if err != nil {
+				return err
+			}
+			return ob.Add(tx, e)
+		})
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	// id 2 首次失败后重试成功，id 3 始终失败转为死信
+	calls := map[int64]int{}
+	var dead []int64
+	d := ob.Dispatcher(outbox.PublisherFunc(func(ctx context.Context, e outbox.Event) error {
+		// 发布在领取事务提交之后，事件处于租约中
+		leased, err := db.Count("select count(*) from outbox where id = ? and status = ? and next_at > ?", e.ID, outbox.StatusPending, time.Now().UnixMilli())
+		if err != nil || leased != 1 {
+			t.Errorf("lease %d: %d %v", e.ID, leased, err)
+		}
+		calls[e.ID]++
+		if e.ID == 3 || (e.ID == 2 && calls[e.ID] == 1) {
+			return errors.New("unavailable")
+		}
+		return nil
+	}))
+	d.Backoff, d.MaxAttempts = 0, 2
+	d.DeadLetter = outbox.PublisherFunc(func(ctx context.Context, e outbox.Event) error {
+		dead = append(dead, e.ID)
+		return nil
+	})
+	for range 3 {
+		if _, err = d.DispatchOnce(context.Background()); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	if calls[1] != 1 || calls[2] != 2 || calls[3] != 2 || len(dead) != 1 || dead[0] != 3 {
+		t.Fatalf("calls %v dead %v", calls, dead)
+	}
+	sent, err := db.Count("select count(*) from outbox where status = ?", outbox.StatusSent)
+	if err != nil || sent != 2 {
+		t.Fatalf("sent %d %v", sent, err)
+	}
+
+	// 死信重新投递到 webhook
+	got := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Header.Get("X-Outbox-Id")
+	}))
+	defer srv.Close()
+	if n, err := ob.Requeue(3); err != nil || n != 1 {
+		t.Fatalf("requeue: %d %v", n, err)
+	}
+	d = ob.Dispatcher(outbox.Webhook(srv.URL, nil))
+	d.Interval = 10 * time.Millisecond
+	d.Start(context.Background())
+	defer d.Stop()
+	select {
+	case id := <-got:
+		if id != "3" {
+			t.Fatalf("webhook id %s", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook not called")
+	}
+}
+
+func Test_OutboxClaimDialect(t *testing.T) {
+	columns := "id,topic,event_key,payload,attempts,created_at"
+	cases := []struct {
+		typ    string
+		claim  string
+		lease  string
+		status string
+	}{
+		{"pgsql", "select " + columns + " from outbox where status = $1 and next_at <= $2 order by id limit 100 for update skip locked", "update outbox set next_at = $1 where id in ($2,$3)", "update outbox set status = $1, last_error = null where id = $2"},
+		{"mysql", "select " + columns + " from outbox where status = ? and next_at <= ? order by id limit 100 for update skip locked", "update outbox set next_at = ? where id in (?,?)", "update outbox set status = ?, last_error = null where id = ?"},
+		{"mssql", "select top 100 " + columns + " from outbox with (updlock, readpast, rowlock) where status = @p1 and next_at <= @p2 order by id", "update outbox set next_at = @p1 where id in (@p2,@p3)", "update outbox set status = @p1, last_error = null where id = @p2"},
+	}
+	for _, c := range cases {
+		rec := initFake(t, DB.Config{Type: c.typ, Role: "master"})
+		rec.Expect(columns).Rows(strings.Split(columns, ","),
+			[]any{int64(1), "stock", "", []byte("a"), int64(0), time.Now().UnixMilli()},
+			[]any{int64(2), "stock", "", []byte("b"), int64(0), time.Now().UnixMilli()},
+		)
+		d := outbox.New(DB.GetMaster).Dispatcher(outbox.PublisherFunc(func(context.Context, outbox.Event) error { return nil }))
+		if n, err := d.DispatchOnce(context.Background()); err != nil || n != 2 {
+			t.Fatalf("%s: %d %v", c.typ, n, err)
+		}
+		// 领取在短事务中加锁并写入租约，提交后再逐条更新状态
+		var got []string
+		for _, call := range rec.Calls() {
+			got = append(got, call.Sql)
+		}
+		want := []string{"BEGIN", c.claim, c.lease, "COMMIT", c.status, c.status}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s\n got: %q\nwant: %q", c.typ, got, want)
+		}
+		if ids := rec.Calls()[2].Args[1:]; !reflect.DeepEqual(ids, []any{int64(1), int64(2)}) {
+			t.Fatalf("%s lease ids: %v", c.typ, ids)
+		}
+		DB.Stop()
+	}
+}
@@ -0,0 +1,107 @@
+package qiao
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+type Orders struct {
+	Id     int64        `db:"id;Autoincrement"`
+	No     string       `db:"no"`
+	Items  []OrderItem  `rel:"hasMany,foreignKey=order_id"`
+	Detail *OrderDetail `rel:"hasOne,foreignKey=order_id"`
+	Tags   []*Tag       `rel:"manyToMany,joinTable=order_tag,foreignKey=order_id,references=tag_id"`
+}
+
+type OrderItem struct {
+	Id      int64  `db:"id"`
+	OrderId int64  `db:"order_id"`
+	Sku     string `db:"sku"`
+}
+
+type OrderDetail struct {
+	OrderId int64  `db:"order_id"`
+	Address string `db:"address"`
+}
+
+type Tag struct {
+	Id   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+func Test_Preload(t *testing.T) {
+	initSqlite(t, 0)
+	db := DB.GetMaster()
+	for _, s := range []string{
+		"create table orders (id integer primary key, no text)",
+		"create table order_item (id integer primary key, order_id int, sku text)",
+		"create table order_detail (order_id int, address text)",
+		"create table tag (id integer primary key, name text)",
+		"create table order_tag (order_id int, tag_id int)",
+		"insert into orders values (1, 'A'), (2, 'B'), (3, 'C')",
+		"insert into order_item values (1, 1, 'x'), (2, 1, 'y'), (3, 2, 'z')",
+		"insert into order_detail values (2, 'Shanghai')",
+		"insert into tag values (1, 'vip'), (2, 'gift')",
+		"insert into order_tag values (1, 1), (1, 2), (3, 2)",
+	} {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+	}
+
+	var list []Orders
+	if err := DB.QiaoDB().Preload("Items", "Detail", "Tags").OrderBy("id").GetList(&list); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(list) != 3 || len(list[0].Items) != 2 || len(list[1].Items) != 1 || len(list[2].Items) != 0 {
+		t.Fatalf("items: %+v", list)
+	}
+	if list[0].Detail != nil || list[1].Detail == nil || list[1].Detail.Address != "Shanghai" {
+		t.Fatalf("detail: %+v", list)
+	}
+	if len(list[0].Tags) != 2 || len(list[1].Tags) != 0 || list[2].Tags[0].Name != "gift" {
+		t.Fatalf("tags: %+v", list)
+	}
+
+	var one Orders
+	if err := DB.QiaoDB().Preload("Items").Find(DB.Eq("id", 2)).Get(&one); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(one.Items) != 1 || one.Items[0].Sku != "z" {
+		t.Fatalf("one: %+v", one)
+	}
+
+	if err := DB.QiaoDB().Preload("No").GetList(&list); err == nil {
+		t.Fatal("expected relation error")
+	}
+}
+
+func Test_PreloadChunk(t *testing.T) {
+	rec := initFake(t, DB.Config{Title: "mssql", Type: "mssql", Role: "master"})
+	var rows [][]any
+	for i := range 2500 {
+		rows = append(rows, []any{i + 1, "no"})
+	}
+	rec.Expect("from orders").Rows([]string{"id", "no"}, rows...)
+	rec.Expect("from order_item").Rows([]string{"id", "order_id", "sku"}, []any{1, 1, "x"})
+	rec.Expect("from order_item").Rows([]string{"id", "order_id", "sku"}, []any{2, 2500, "y"})
+
+	var list []Orders
+	if err := DB.QiaoDB().Preload("Items").GetList(&list); err != nil {
+		t.Fatalf("%v", err)
+	}
+	var sizes []int
+	for _, c := range rec.Calls() {
+		if strings.Contains(c.Sql, "order_item") {
+			sizes = append(sizes, len(c.Args))
+		}
+	}
+	if len(sizes) != 2 || sizes[0] != 2000 || sizes[1] != 500 {
+		t.Fatalf("chunks: %v", sizes)
+	}
+	if len(list[0].Items) != 1 || list[2499].Items[0].Sku != "y" || len(list[1].Items) != 0 {
+		t.Fatalf("items: %+v %+v", list[0], list[2499])
+	}
+}
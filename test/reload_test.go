@@ -0,0 +1,115 @@
+package qiao
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+func Test_Reload(t *testing.T) {
+	dir := t.TempDir()
+	conf := func(id int, role, name string) DB.Config {
+		return DB.Config{ID: id, Title: name, Type: "sqlite", Role: role, Open: true, Dsn: filepath.Join(dir, name+".db")}
+	}
+	if err := DB.InitDB(false, 0, 0, conf(1, "master", "a")); err != nil {
+		t.Fatalf("%v", err)
+	}
+	t.Cleanup(DB.Stop)
+	drain := DB.ReloadDrain
+	DB.ReloadDrain = 50 * time.Millisecond
+	t.Cleanup(func() { DB.ReloadDrain = drain })
+
+	old := DB.GetMaster()
+	if err := DB.Reload(conf(1, "master", "a"), conf(2, "slave", "b")); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if DB.GetMaster().DBFunc.Conn != old.DBFunc.Conn || DB.GetSlave() == nil || DB.Pool.PoolCount != 2 {
+		t.Fatal("unchanged connection should be kept")
+	}
+
+	// 变更的连接替换后，旧连接在 drain 期间仍可使用
+	if err := DB.Reload(conf(1, "master", "c")); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if DB.GetMaster().Conf.Title != "c" || DB.GetSlave() != nil {
+		t.Fatalf("pool: %+v", DB.Pool)
+	}
+	if _, err := old.Exec("create table t (id integer)"); err != nil {
+		t.Fatalf("in-flight: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := old.DBFunc.Conn.Ping(); err == nil {
+		t.Fatal("replaced connection should be closed after drain")
+	}
+
+	if err := DB.Reload(conf(1, "master", "c"), DB.Config{ID: 3, Type: "oracle", Open: true}); err == nil {
+		t.Fatal("expected open error")
+	}
+	if DB.GetMaster().Conf.Title != "c" {
+		t.Fatal("failed reload should keep current pool")
+	}
+
+	// 保留的连接与连接池共用同一 *ConnDB，离线状态不会丢失
+	kept := DB.GetMaster()
+	if err := DB.Reload(conf(1, "master", "c"), conf(2, "slave", "b")); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if DB.GetMaster() != kept {
+		t.Fatal("kept connection should be the same *ConnDB")
+	}
+	if err := DB.Reload(conf(0, "master", "c")); err == nil {
+		t.Fatal("expected zero ID error")
+	}
+}
+
+func Test_ReloadWithoutID(t *testing.T) {
+	dir := t.TempDir()
+	conf := func(role, name string) DB.Config {
+		return DB.Config{Title: name, Type: "sqlite", Role: role, Open: true, Dsn: filepath.Join(dir, name+".db")}
+	}
+	if err := DB.InitDB(false, 0, 0, conf("master", "a"), conf("slave", "b")); err != nil {
+		t.Fatalf("%v", err)
+	}
+	t.Cleanup(DB.Stop)
+	c := conf("master", "a")
+	c.ID = 1
+	if err := DB.Reload(c); err == nil {
+		t.Fatal("pool without IDs should not be reloaded")
+	}
+	if DB.GetSlave() == nil || DB.Pool.PoolCount != 2 {
+		t.Fatal("rejected reload should keep current pool")
+	}
+}
+
+func Test_WatchConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "db.yml")
+	write := func(name string) {
+		body := fmt.Sprintf("Databases:\n  - {ID: 1, Title: %s, Type: sqlite, Role: master, Open: true, Dsn: %s}\n", name, filepath.Join(dir, name+".db"))
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	write("a")
+	if err := DB.InitDBFile(path); err != nil {
+		t.Fatalf("%v", err)
+	}
+	t.Cleanup(DB.Stop)
+	stop := DB.WatchConfig(path, 10*time.Millisecond, nil)
+	defer stop()
+
+	time.Sleep(20 * time.Millisecond)
+	write("b")
+	os.Chtimes(path, time.Now(), time.Now().Add(time.Second))
+	for range 100 {
+		if db := DB.GetMaster(); db != nil && db.Conf.Title == "b" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("config change not reloaded")
+}
@@ -0,0 +1,67 @@
+package qiao
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+func Test_ResultSets(t *testing.T) {
+	initSqlite(t, 0)
+	if _, err := DB.GetMaster().Exec("create table item (id integer primary key, name text)"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	for i := range 3 {
+		if _, err := DB.QiaoDB().Add(&Item{Id: int64(i), Name: "name"}); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	mapper, err := DB.QiaoDB().Query("select id, name from item order by id")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var items []Item
+	if err = mapper.ResultSets(&items); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(items) != 3 || items[2].Id != 2 {
+		t.Fatalf("items: %+v", items)
+	}
+
+	var count int
+	rows, err := DB.GetMaster().Query("select count(*), max(id) from item")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err = DB.ResultSets(rows, &count, nil); !errors.Is(err, DB.ErrNoResultSet) {
+		t.Fatalf("err: %v", err)
+	}
+	if count != 3 {
+		t.Fatalf("count: %d", count)
+	}
+}
+
+func Test_ResultSetsMultiple(t *testing.T) {
+	rec := initFake(t, DB.Config{Title: "mssql", Type: "mssql", Role: "master"})
+	rec.Expect("report").
+		Rows([]string{"id", "name"}, []any{1, "a"}, []any{2, "b"}).
+		Rows([]string{"total"}, []any{2}).
+		Rows([]string{"id", "name"}, []any{3, "c"}).
+		Rows(nil)
+	rows, err := DB.GetMaster().Query("exec report")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var items []Item
+	var total int
+	var row map[string]any
+	var last int
+	if err = DB.ResultSets(rows, &items, &total, &row, &last); !errors.Is(err, DB.ErrNoColumns) {
+		t.Fatalf("err: %v", err)
+	}
+	if len(items) != 2 || items[1].Name != "b" || total != 2 || row["name"] != "c" {
+		t.Fatalf("sets: %+v %d %v", items, total, row)
+	}
+}
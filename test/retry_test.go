@@ -0,0 +1,107 @@
+package qiao
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chris-liu-zh/qiao/DB"
+	"github.com/go-sql-driver/mysql"
+)
+
+func initRetry(t *testing.T) *DB.Recorder {
+	rec := initFake(t, DB.Config{Title: "mysql", Type: "mysql", Role: "master"})
+	DB.SetRetry(DB.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond})
+	t.Cleanup(func() {
+		DB.SetRetry(DB.RetryPolicy{MaxAttempts: 3, Backoff: 50 * time.Millisecond, MaxBackoff: time.Second})
+	})
+	return rec
+}
+
+func Test_RetryStatement(t *testing.T) {
+	rec := initRetry(t)
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+
+	rec.Expect("update").Error(deadlock)
+	rec.Expect("update").Error(deadlock)
+	rec.Expect("update").Result(0, 1)
+	affected, err := DB.GetMaster().Affected("update item set name = ? where id = ?", "a", 1)
+	if err != nil || affected != 1 || len(rec.Calls()) != 3 {
+		t.Fatalf("affected %d, err %v, calls %d", affected, err, len(rec.Calls()))
+	}
+
+	// 超过最大次数返回最后的错误
+	rec.Reset()
+	for range 3 {
+		rec.Expect("update").Error(deadlock)
+	}
+	if _, err = DB.GetMaster().Exec("update item set name = ?", "a"); !errors.Is(err, deadlock) || len(rec.Calls()) != 3 {
+		t.Fatalf("err %v, calls %d", err, len(rec.Calls()))
+	}
+
+	// 不可重试的错误只执行一次
+	rec.Reset()
+	rec.Expect("update").Error(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+	if _, err = DB.GetMaster().Exec("update item set name = ?", "a"); err == nil || len(rec.Calls()) != 1 {
+		t.Fatalf("err %v, calls %d", err, len(rec.Calls()))
+	}
+}
+
+// 与执行中的语句并发修改策略，go test -race 下不报数据竞争
+func Test_RetrySetConcurrent(t *testing.T) {
+	initRetry(t)
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Go(func() {
+			for range 50 {
+				if i%2 == 0 {
+					DB.SetRetry(DB.RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond})
+				} else if _, err := DB.GetMaster().Exec("update item set name = ?", "a"); err != nil {
+					t.Errorf("%v", err)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func Test_RetryTransaction(t *testing.T) {
+	rec := initRetry(t)
+	rec.Expect("update").Error(&mysql.MySQLError{Number: 1213})
+	rec.Expect("update").Result(0, 1)
+
+	var runs int
+	err := DB.QiaoDB().Transaction(func(tx *DB.Begin) error {
+		runs++
+		_, err := tx.ExecSql("update item set name = ? where id = ?", "a", 1)
+		return err
+	})
+	if err != nil || runs != 2 {
+		t.Fatalf("runs %d, err %v", runs, err)
+	}
+	var got []string
+	for _, c := range rec.Calls() {
+		got = append(got, c.Sql[:min(len(c.Sql), 6)])
+	}
+	want := []string{"BEGIN", "update", "ROLLBA", "BEGIN", "update", "COMMIT"}
+	if len(got) != len(want) {
+		t.Fatalf("calls: %v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("calls: %v", got)
+		}
+	}
+
+	// fn 返回错误时回滚且不重试
+	rec.Reset()
+	errStop := errors.New("stop")
+	if err = DB.QiaoDB().Transaction(func(tx *DB.Begin) error { return errStop }); !errors.Is(err, errStop) {
+		t.Fatalf("err: %v", err)
+	}
+	if calls := rec.Calls(); len(calls) != 2 || calls[1].Sql != "ROLLBACK" {
+		t.Fatalf("calls: %+v", calls)
+	}
+}
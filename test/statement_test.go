@@ -0,0 +1,80 @@
+package qiao
+
+import (
+	"embed"
+	"errors"
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+//go:embed testdata/statements
+var statementFS embed.FS
+
+func Test_Statement(t *testing.T) {
+	initSqlite(t, 0)
+	if err := DB.LoadStatementDir("testdata/statements"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	db := DB.GetMaster()
+	if _, err := db.Exec("create table item (id integer primary key, name text)"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	for i, name := range []string{"apple", "avocado", "banana"} {
+		if _, err := db.Exec("insert into item (id, name) values (?, ?)", i+1, name); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	var list []Item
+	m := DB.QiaoDB().Statement("item.findActive", map[string]any{"name": "a%", "minId": 0, "order": "id desc"})
+	if err := m.GetList(&list); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(list) != 2 || list[0].Name != "avocado" {
+		t.Fatalf("list: %+v", list)
+	}
+	if sql, _ := m.GetSql(); len(sql.Args) != 1 {
+		t.Fatalf("args: %+v", sql)
+	}
+
+	// ${} 只允许标识符
+	err := DB.QiaoDB().Statement("item.findActive", map[string]any{"name": "", "minId": 0, "order": "id; drop table item"}).GetList(&list)
+	if err == nil {
+		t.Fatal("expected identifier error")
+	}
+
+	if _, err = DB.QiaoDB().Statement("item.rename", Item{Id: 3, Name: "blueberry"}).Exec(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	var item Item
+	if err = DB.QiaoDB().Statement("items.byIds", map[string]any{"ids": []int{3}, "skipOrder": true}).Get(&item); err != nil || item.Name != "blueberry" {
+		t.Fatalf("item: %+v %v", item, err)
+	}
+	list = nil
+	if err = DB.QiaoDB().Statement("items.byIds", map[string]any{"ids": []int64{1, 2}}).GetList(&list); err != nil || len(list) != 2 || list[0].Id != 2 {
+		t.Fatalf("list: %+v %v", list, err)
+	}
+
+	if err = DB.QiaoDB().Statement("item.missing", nil).GetList(&list); !errors.Is(err, DB.ErrStatementNotFound) {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func Test_StatementEmbed(t *testing.T) {
+	initSqlite(t, 0)
+	if err := DB.LoadStatements(statementFS); err != nil {
+		t.Fatalf("%v", err)
+	}
+	for _, s := range []string{"create table item (id integer primary key, name text)", "insert into item (id, name) values (1, 'a'), (20, 'b')"} {
+		if _, err := DB.GetMaster().Exec(s); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	var count struct {
+		N int `db:"n"`
+	}
+	if err := DB.QiaoDB().Statement("items.countBelow", map[string]any{"max": 10}).Get(&count); err != nil || count.N != 1 {
+		t.Fatalf("count: %d %v", count.N, err)
+	}
+}
@@ -0,0 +1,45 @@
+package qiao
+
+import (
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+func initSqlite(t *testing.T, stmtCache int) {
+	t.Helper()
+	conf := DB.Config{
+		Title:     "sqlite",
+		Role:      "master",
+		Type:      "sqlite",
+		Open:      true,
+		Dsn:       t.TempDir() + "/test.db",
+		StmtCache: stmtCache,
+	}
+	if err := DB.InitDB(false, 0, 0, conf); err != nil {
+		t.Fatalf("%v", err)
+	}
+	t.Cleanup(DB.Stop)
+}
+
+func Test_StmtCache(t *testing.T) {
+	initSqlite(t, 2)
+	db := DB.GetMaster()
+	if _, err := db.Exec("create table item (id integer primary key, name text)"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	for i := range 3 {
+		if _, err := db.Exec("insert into item (id, name) values (?, ?)", i, "name"); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	for range 2 {
+		if _, err := db.Count("select count(*) from item where id > ?", 0); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	stats := db.StmtStats()
+	if stats.Hits != 3 || stats.Misses != 3 || stats.Size != 2 {
+		t.Fatalf("stats: %+v", stats)
+	}
+}
@@ -0,0 +1,107 @@
+package qiao
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+func Test_Subquery(t *testing.T) {
+	active := DB.QiaoDB().Table("users").Field("id").Find(DB.Eq("status", 1))
+	orders := DB.QiaoDB().Table("orders o").Field("count(*)").Find("o.user_id = u.id and o.amount > ?", 100)
+	complete, err := DB.QiaoDB().Table("users u").Field("u.id").
+		Find(DB.In("u.id", active)).
+		SubField(orders, "order_count").
+		With("recent", DB.QiaoDB().Table("orders").Field("user_id").Find(DB.Gt("created", "2025-01-01"))).
+		UnionAll(DB.QiaoDB().Table("admins").Field("id,0").Find(DB.Eq("level", 9))).
+		GetSql()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	want := "with recent as (select user_id from orders where (created > ?)) " +
+		"select u.id,(select count(*) from orders o where (o.user_id = u.id and o.amount > ?)) as order_count " +
+		"from users u where (u.id in (select id from users where (status = ?))) " +
+		"union all select id,0 from admins where (level = ?)"
+	if got := strings.Join(strings.Fields(complete.Sql), " "); got != want {
+		t.Fatalf("sql\n got: %s\nwant: %s", got, want)
+	}
+	if !reflect.DeepEqual(complete.Args, []any{"2025-01-01", 100, 1, 9}) {
+		t.Fatalf("args: %v", complete.Args)
+	}
+	if got := DB.Replace(complete.Sql, "?", "$"); !strings.Contains(got, "level = $4") {
+		t.Fatalf("placeholder: %s", got)
+	}
+}
+
+type TreeNode struct {
+	Id    int64 `db:"id"`
+	Depth int64 `db:"depth"`
+}
+
+func Test_RecursiveCTE(t *testing.T) {
+	initSqlite(t, 0)
+	db := DB.GetMaster()
+	for _, s := range []string{
+		"create table node (id integer primary key, parent_id integer)",
+		"insert into node (id, parent_id) values (1, null), (2, 1), (3, 2), (4, 1)",
+	} {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	tree := DB.QiaoDB().Table("node").Field("id, 0").Find(DB.Eq("id", 2)).
+		UnionAll(DB.QiaoDB().Table("node n").Field("n.id, t.depth + 1").InnerJoin("tree", "t", "n.parent_id = t.id"))
+	var nodes []TreeNode
+	if err := DB.QiaoDB().WithRecursive("tree(id, depth)", tree).Table("tree").GetList(&nodes); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(nodes) != 2 || nodes[1].Id != 3 || nodes[1].Depth != 1 {
+		t.Fatalf("nodes: %+v", nodes)
+	}
+
+	tree = DB.QiaoDB().Table("node").Field("id, 0").Find(DB.Eq("id", 2)).
+		UnionAll(DB.QiaoDB().Table("node n").Field("n.id, t.depth + 1").InnerJoin("tree", "t", "n.parent_id = t.id"))
+	count, err := DB.QiaoDB().WithRecursive("tree(id, depth)", tree).Table("tree").Count(&TreeNode{}, "")
+	if err != nil || count != 2 {
+		t.Fatalf("count: %d %v", count, err)
+	}
+
+	nodes = nil
+	err = DB.QiaoDB().Table("node").Field("id, 0 as depth").Find(DB.Eq("id", 1)).
+		UnionAll(DB.QiaoDB().Table("node").Field("id, 1").Find(DB.Gt("id", 2))).
+		OrderBy("id desc").Limit(2, 1).GetList(&nodes)
+	if err != nil || len(nodes) != 2 || nodes[0].Id != 4 || nodes[1].Id != 3 {
+		t.Fatalf("union: %+v %v", nodes, err)
+	}
+}
+
+func Test_SubqueryOrder(t *testing.T) {
+	rec := initFake(t, DB.Config{Title: "pgsql", Type: "pgsql", Role: "master"})
+	rec.Expect("count").Rows([]string{"count"}, []any{2})
+	rec.Expect("union").Rows([]string{"id"}, []any{1})
+
+	recent := DB.QiaoDB().Table("orders").Field("user_id").Find(DB.Gt("created", "2025-01-01"))
+	count, err := DB.QiaoDB().With("recent", recent).Table("users").Find(DB.Eq("status", 1)).Count(&Item{}, "")
+	if err != nil || count != 2 {
+		t.Fatalf("count: %d %v", count, err)
+	}
+	want := "with recent as (select user_id from orders where (created > $1)) select count(*) from(select id,name from users where (status = $2) ) a"
+	if last := rec.Last(); strings.Join(strings.Fields(last.Sql), " ") != strings.Join(strings.Fields(want), " ") || !reflect.DeepEqual(last.Args, []any{"2025-01-01", 1}) {
+		t.Fatalf("count sql: %s %v", last.Sql, last.Args)
+	}
+
+	// 排序、分页作用于集合运算的结果
+	var list []Item
+	err = DB.QiaoDB().Table("users").Field("id").Find(DB.Eq("status", 1)).
+		UnionAll(DB.QiaoDB().Table("admins").Field("id").Find(DB.Eq("level", 9))).
+		OrderBy("id").Limit(10, 2).GetList(&list)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	want = "select * from (select id from users where (status = $1) union all select id from admins where (level = $2)) u order by id LIMIT $3 OFFSET $4"
+	if last := rec.Last(); strings.Join(strings.Fields(last.Sql), " ") != want || !reflect.DeepEqual(last.Args, []any{1, 9, 10, 10}) {
+		t.Fatalf("union sql: %s %v", last.Sql, last.Args)
+	}
+}
@@ -0,0 +1,127 @@
+package qiao
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chris-liu-zh/qiao/DB"
+)
+
+type Note struct {
+	Id       int64  `db:"id;Autoincrement"`
+	TenantId int64  `db:"tenant_id;tenant"`
+	Title    string `db:"title"`
+}
+
+func Test_Tenant(t *testing.T) {
+	initSqlite(t, 0)
+	DB.Tenant(&Note{})
+	if _, err := DB.GetMaster().Exec("create table note (id integer primary key, tenant_id int, title text)"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	t1 := DB.WithTenant(context.Background(), int64(1))
+	t2 := DB.WithTenant(context.Background(), int64(2))
+
+	for _, c := range []struct {
+		ctx   context.Context
+		title string
+	}{{t1, "a"}, {t1, "b"}, {t2, "c"}} {
+		if _, err := DB.QiaoDB().Context(c.ctx).Add(&Note{Title: c.title}); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	if _, err := DB.QiaoDB().Context(t1).Add(&Note{TenantId: 2, Title: "x"}); !errors.Is(err, DB.ErrTenantMismatch) {
+		t.Fatalf("mismatch: %v", err)
+	}
+
+	var list []Note
+	if err := DB.QiaoDB().Context(t1).GetList(&list); err != nil || len(list) != 2 || list[0].TenantId != 1 {
+		t.Fatalf("list: %+v %v", list, err)
+	}
+	if err := DB.QiaoDB().GetList(&list); !errors.Is(err, DB.ErrNoTenant) {
+		t.Fatalf("no tenant: %v", err)
+	}
+
+	// 其他租户的数据不受更新、删除影响
+	affected, err := DB.QiaoDB().Context(t2).Table("note").Find(DB.Eq("title", "a")).UpdateAffected(map[string]any{"title": "hacked"})
+	if err != nil || affected != 0 {
+		t.Fatalf("update: %d %v", affected, err)
+	}
+	if affected, err = DB.QiaoDB().Context(t2).Table("note").DelAffected(DB.Ne("id", 0)); err != nil || affected != 1 {
+		t.Fatalf("del: %d %v", affected, err)
+	}
+
+	list = nil
+	if err = DB.QiaoDB().WithoutTenant().OrderBy("id").GetList(&list); err != nil || len(list) != 2 || list[0].Title != "a" {
+		t.Fatalf("without tenant: %+v %v", list, err)
+	}
+	var count int
+	if count, err = DB.QiaoDB().Context(t1).Table("note").Alias("n").Count(&Note{}, ""); err != nil || count != 2 {
+		t.Fatalf("count: %d %v", count, err)
+	}
+
+	// 不能通过 Set 把数据移到其他租户
+	if _, err = DB.QiaoDB().Context(t1).Table("note").Find(DB.Eq("title", "a")).UpdateAffected(map[string]any{"tenant_id": 2}); !errors.Is(err, DB.ErrTenantColumn) {
+		t.Fatalf("set map: %v", err)
+	}
+	if _, err = DB.QiaoDB().Context(t1).Table("note").Alias("n").Find(DB.Eq("title", "a")).UpdateAffected("title = ?, n.tenant_id = ?", "a", 2); !errors.Is(err, DB.ErrTenantColumn) {
+		t.Fatalf("set string: %v", err)
+	}
+
+	// BulkLoad 填充租户列，租户不一致时整体回滚
+	rows := func(yield func([]any) bool) {
+		if yield([]any{"d"}) {
+			yield([]any{"e"})
+		}
+	}
+	if affected, err = DB.QiaoDB().Context(t1).BulkLoad("note", []string{"title"}, rows); err != nil || affected != 2 {
+		t.Fatalf("bulk load: %d %v", affected, err)
+	}
+	if count, err = DB.QiaoDB().Context(t1).Count(&Note{}, ""); err != nil || count != 4 {
+		t.Fatalf("bulk count: %d %v", count, err)
+	}
+	mixed := func(yield func([]any) bool) {
+		if yield([]any{"f", 1}) {
+			yield([]any{"g", 2})
+		}
+	}
+	if _, err = DB.QiaoDB().Context(t1).BulkLoad("note", []string{"title", "tenant_id"}, mixed); !errors.Is(err, DB.ErrTenantMismatch) {
+		t.Fatalf("bulk mismatch: %v", err)
+	}
+	if _, err = DB.BulkLoad("note", []string{"title"}, rows); !errors.Is(err, DB.ErrNoTenant) {
+		t.Fatalf("bulk no tenant: %v", err)
+	}
+	if count, err = DB.QiaoDB().WithoutTenant().Count(&Note{}, ""); err != nil || count != 4 {
+		t.Fatalf("total: %d %v", count, err)
+	}
+}
+
+type TaggedNote struct {
+	Id   int64  `db:"id"`
+	Tags []*Tag `rel:"manyToMany,joinTable=note_tag,foreignKey=note_id,references=tag_id"`
+}
+
+func Test_TenantJoinTable(t *testing.T) {
+	rec := initFake(t, DB.Config{Title: "pgsql", Type: "pgsql", Role: "master"})
+	DB.Tenant("note_tag")
+	rec.Expect("from tagged_note").Rows([]string{"id"}, []any{1})
+	rec.Expect("from note_tag").Rows([]string{"note_id", "tag_id"}, []any{1, 5})
+	rec.Expect("from tag").Rows([]string{"id", "name"}, []any{5, "vip"})
+
+	var list []TaggedNote
+	ctx := DB.WithTenant(context.Background(), int64(7))
+	if err := DB.QiaoDB().Context(ctx).Preload("Tags").GetList(&list); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(list) != 1 || len(list[0].Tags) != 1 {
+		t.Fatalf("list: %+v", list)
+	}
+	for _, c := range rec.Calls() {
+		if strings.Contains(c.Sql, "note_tag") && (!strings.Contains(c.Sql, "tenant_id = $2") || !reflect.DeepEqual(c.Args, []any{int64(1), int64(7)})) {
+			t.Fatalf("join table: %s %#v", c.Sql, c.Args)
+		}
+	}
+}
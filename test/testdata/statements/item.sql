@@ -0,0 +1,15 @@
+-- name: findActive
+select id,name from item
+<where>
+	<if test="name != null and name != ''">and name like #{name}</if>
+	<if test="minId > 0">and id >= #{minId}</if>
+	and id < 100
+</where>
+order by ${order}
+
+-- name: rename
+update item
+<set>
+	<if test="Name != ''">name = #{Name},</if>
+</set>
+where id = #{Id}
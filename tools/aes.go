@@ -51,7 +51,7 @@ func AESDecrypt(decodeString, aesKey string) (decrypted string, err error) {
 	if trim < 0 {
 		return "", errors.New("密钥或加密数据不正确！")
 	}
-	return string(decryptedByte), nil
+	return string(decryptedByte[:trim]), nil
 }
 
 func generateKey(key []byte) (genKey []byte) {
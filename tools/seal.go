@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	"github.com/tjfoc/gmsm/sm4"
+)
+
+var ErrSealed = errors.New("密钥或加密数据不正确！")
+
+// SealAES AES-256-GCM 加密，密钥由 key 经 HKDF-SHA256 派生，结果为 base64(nonce+密文)
+func SealAES(plain, key string) (string, error) {
+	return seal(plain, key, "qiao aes-gcm", 32, aes.NewCipher)
+}
+
+// OpenAES 解密 SealAES 的结果
+func OpenAES(text, key string) (string, error) {
+	return open(text, key, "qiao aes-gcm", 32, aes.NewCipher)
+}
+
+// SealSM4 SM4-GCM 加密，密钥派生与结果格式同 SealAES
+func SealSM4(plain, key string) (string, error) {
+	return seal(plain, key, "qiao sm4-gcm", 16, sm4.NewCipher)
+}
+
+// OpenSM4 解密 SealSM4 的结果
+func OpenSM4(text, key string) (string, error) {
+	return open(text, key, "qiao sm4-gcm", 16, sm4.NewCipher)
+}
+
+func sealAEAD(key, info string, size int, newCipher func([]byte) (cipher.Block, error)) (cipher.AEAD, error) {
+	if key == "" {
+		return nil, errors.New("密钥不能为空")
+	}
+	derived, err := hkdf.Key(sha256.New, []byte(key), nil, info, size)
+	if err != nil {
+		return nil, err
+	}
+	block, err := newCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(plain, key, info string, size int, newCipher func([]byte) (cipher.Block, error)) (string, error) {
+	aead, err := sealAEAD(key, info, size, newCipher)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(plain), nil)), nil
+}
+
+func open(text, key, info string, size int, newCipher func([]byte) (cipher.Block, error)) (string, error) {
+	aead, err := sealAEAD(key, info, size, newCipher)
+	if err != nil {
+		return "", err
+	}
+	raw, err := base64.StdEncoding.DecodeString(text)
+	if err != nil || len(raw) < aead.NonceSize() {
+		return "", ErrSealed
+	}
+	plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], nil)
+	if err != nil {
+		return "", ErrSealed
+	}
+	return string(plain), nil
+}